@@ -0,0 +1,189 @@
+package dexcom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Archive is a complete copy of a receiver's database,
+// together with information identifying the receiver it came from.
+// It is stored as JSON, with the raw page data in base64.
+type Archive struct {
+	Version        int
+	Created        time.Time
+	SystemTime     time.Time
+	DisplayTime    time.Time
+	FirmwareHeader XMLInfo
+	HardwareID     int
+	TransmitterID  string
+	PartitionInfo  *PartitionInfo `json:",omitempty"`
+	Pages          []ArchivePage
+}
+
+// ArchivePage is a raw database page, as returned by ReadPage.
+type ArchivePage struct {
+	Type   PageType
+	Number int
+	Data   []byte
+}
+
+const archiveVersion = 1
+
+// Backup reads every page of every type from the receiver
+// and returns them as an Archive.
+func (cgm *CGM) Backup() *Archive {
+	if cgm.Error() != nil {
+		return nil
+	}
+	a, err := cgm.BackupE()
+	cgm.SetError(err)
+	return a
+}
+
+// BackupE is like Backup, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) BackupE() (*Archive, error) {
+	a := &Archive{Version: archiveVersion}
+	var err error
+	a.FirmwareHeader, err = cgm.ReadFirmwareHeaderE()
+	if err != nil {
+		return nil, err
+	}
+	a.HardwareID, err = cgm.ReadHardwareIDE()
+	if err != nil {
+		return nil, err
+	}
+	a.TransmitterID, err = cgm.ReadTransmitterIDE()
+	if err != nil {
+		return nil, err
+	}
+	a.SystemTime, err = cgm.ReadSystemTimeE()
+	if err != nil {
+		return nil, err
+	}
+	a.DisplayTime, err = cgm.ReadDisplayTimeE()
+	if err != nil {
+		return nil, err
+	}
+	a.PartitionInfo = cgm.partitionInfo(context.Background())
+	for pageType := FirstPageType; pageType <= LastPageType; pageType++ {
+		first, last, err := cgm.ReadPageRangeE(pageType)
+		if err != nil {
+			return nil, err
+		}
+		if first < 0 {
+			continue
+		}
+		pages, err := cgm.readPages(context.Background(), pageType, first, last-first+1)
+		if err != nil {
+			return nil, fmt.Errorf("%v pages %d to %d: %v", pageType, first, last, err)
+		}
+		for i, v := range pages {
+			a.Pages = append(a.Pages, ArchivePage{Type: pageType, Number: first + i, Data: v})
+		}
+	}
+	a.Created = time.Now()
+	return a, nil
+}
+
+// Write writes the archive to w.
+func (a *Archive) Write(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(a)
+}
+
+// Save writes the archive to the given file.
+func (a *Archive) Save(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	err = a.Write(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadArchive reads an archive written by Archive.Write.
+func ReadArchive(r io.Reader) (*Archive, error) {
+	a := &Archive{}
+	err := json.NewDecoder(r).Decode(a)
+	if err != nil {
+		return nil, err
+	}
+	if a.Version != archiveVersion {
+		return nil, fmt.Errorf("unsupported archive version (%d)", a.Version)
+	}
+	return a, nil
+}
+
+// LoadArchive reads an archive from the given file.
+func LoadArchive(file string) (*Archive, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadArchive(f)
+}
+
+// PageRange returns the first and last archived page of the given type,
+// or -1 and -1 if there are none.
+func (a *Archive) PageRange(pageType PageType) (int, int) {
+	first, last := -1, -1
+	for _, p := range a.Pages {
+		if p.Type != pageType {
+			continue
+		}
+		if first == -1 || p.Number < first {
+			first = p.Number
+		}
+		if last == -1 || p.Number > last {
+			last = p.Number
+		}
+	}
+	return first, last
+}
+
+// Page returns the raw data of the specified archived page.
+func (a *Archive) Page(pageType PageType, pageNumber int) ([]byte, error) {
+	for _, p := range a.Pages {
+		if p.Type == pageType && p.Number == pageNumber {
+			return p.Data, nil
+		}
+	}
+	return nil, fmt.Errorf("%v page %d is not in archive", pageType, pageNumber)
+}
+
+// Records unmarshals the archived pages of the given type
+// and returns their records, most recent first.
+func (a *Archive) Records(pageType PageType) (Records, error) {
+	first, last := a.PageRange(pageType)
+	if first < 0 {
+		return nil, nil
+	}
+	var results Records
+	for n := last; n >= first; n-- {
+		v, err := a.Page(pageType, n)
+		if err != nil {
+			return results, err
+		}
+		page, err := a.PartitionInfo.UnmarshalPage(v)
+		if err != nil {
+			return results, fmt.Errorf("%v page %d: %v", pageType, n, err)
+		}
+		records, err := UnmarshalRecords(pageType, page.Records)
+		results = append(results, records...)
+		if err != nil {
+			return results, fmt.Errorf("%v page %d: %v", pageType, n, err)
+		}
+	}
+	return results, nil
+}
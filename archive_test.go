@@ -0,0 +1,91 @@
+package dexcom
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	cgm.SetTransmitterID("6ABCD")
+	a := cgm.Backup()
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(a.Pages) != len(emulatorPages) {
+		t.Errorf("archive has %d pages, want %d", len(a.Pages), len(emulatorPages))
+	}
+	var buf bytes.Buffer
+	err := a.Write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ReadArchive(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.TransmitterID != "6ABCD" {
+		t.Errorf("TransmitterID == %q, want %q", b.TransmitterID, "6ABCD")
+	}
+	if b.FirmwareHeader["ProductId"] != "G4Receiver" {
+		t.Errorf("FirmwareHeader == %v", b.FirmwareHeader)
+	}
+	if !b.Created.Equal(a.Created) {
+		t.Errorf("Created == %v, want %v", b.Created, a.Created)
+	}
+	for _, c := range emulatorPages {
+		t.Run(c.pageType.String(), func(t *testing.T) {
+			first, last := b.PageRange(c.pageType)
+			if first != c.pageNumber || last != c.pageNumber {
+				t.Errorf("PageRange(%v) == (%d, %d), want (%d, %d)", c.pageType, first, last, c.pageNumber, c.pageNumber)
+			}
+			records, err := b.Records(c.pageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRecords(t, records, testFileName(c)+".json")
+		})
+	}
+	records, err := b.Records(UserEventData)
+	if err != nil || len(records) != 0 {
+		t.Errorf("Records(%v) == %v, %v; want no records", UserEventData, records, err)
+	}
+}
+
+func TestArchivePageRequests(t *testing.T) {
+	const first, count = 310, 7
+	t0 := parseTime("2017-09-17 11:00:00")
+	e := NewEmulator()
+	for n := first; n < first+count; n++ {
+		err := e.AddPage(makePage(EGVData, n, testRecord(t0.Add(time.Duration(n)*5*time.Minute), &EGVInfo{Glucose: 100})))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn := &countingConn{Emulator: e}
+	cgm := &CGM{Connection: conn}
+	a := cgm.Backup()
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(a.Pages) != count {
+		t.Fatalf("archive has %d pages, want %d", len(a.Pages), count)
+	}
+	for i, p := range a.Pages {
+		if p.Number != first+i {
+			t.Errorf("archive page %d has number %d, want %d", i, p.Number, first+i)
+		}
+	}
+	want := (count + maxPagesPerRead - 1) / maxPagesPerRead
+	if conn.pageReads != want {
+		t.Errorf("Backup used %d page requests, want %d", conn.pageReads, want)
+	}
+}
+
+func TestArchiveVersion(t *testing.T) {
+	_, err := ReadArchive(bytes.NewBufferString(`{"Version": 99}`))
+	if err == nil {
+		t.Errorf("ReadArchive succeeded with unknown version, want error")
+	}
+}
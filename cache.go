@@ -0,0 +1,175 @@
+package dexcom
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// pageCache stores full database pages on disk, in a directory
+// for each receiver, so they need not be read again.
+// Each page is stored in hexadecimal, in the same format
+// as the rawpage command prints, in a file named TYPE.NUMBER.data.
+// Only pages before the last page of each type are cached,
+// since the last page may still be receiving new records.
+// Cached pages outside the receiver's current page range are ignored,
+// since page numbers restart when the database is erased.
+type pageCache struct {
+	mu     sync.Mutex
+	root   string
+	dir    string // receiver-specific directory, once known
+	failed bool   // true if the receiver's directory could not be determined
+	ranges map[PageType]pageRange
+}
+
+// pageRange is the range of pages of one type on the receiver.
+type pageRange struct {
+	first, last int
+}
+
+// full reports whether the given page is in the range
+// and before the last page.
+func (r pageRange) full(pageNumber int) bool {
+	return r.first <= pageNumber && pageNumber < r.last
+}
+
+// SetPageCache enables a persistent cache of database pages
+// in the given directory, or disables it if dir is empty.
+// Pages are stored in a subdirectory named after the receiver's serial number.
+func (cgm *CGM) SetPageCache(dir string) {
+	cgm.cacheMu.Lock()
+	defer cgm.cacheMu.Unlock()
+	if dir == "" {
+		cgm.cache = nil
+		return
+	}
+	cgm.cache = &pageCache{root: dir, ranges: make(map[PageType]pageRange)}
+}
+
+func (cgm *CGM) pageCache() *pageCache {
+	cgm.cacheMu.Lock()
+	defer cgm.cacheMu.Unlock()
+	return cgm.cache
+}
+
+// receiverDir returns the cache directory for the receiver,
+// determining it from the serial number the first time.
+func (c *pageCache) receiverDir(ctx context.Context, cgm *CGM) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir != "" || c.failed {
+		return c.dir
+	}
+	serial, err := cgm.readSerialNumber(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("page cache disabled: %v", err)
+			c.failed = true
+		}
+		return ""
+	}
+	c.dir = filepath.Join(c.root, serial)
+	return c.dir
+}
+
+// readSerialNumber reads the receiver's serial number
+// from its manufacturing data, bypassing the page cache.
+func (cgm *CGM) readSerialNumber(ctx context.Context) (string, error) {
+	v, err := cgm.readPageBlock(ctx, ManufacturingData, 0, 1)
+	if err != nil {
+		return "", err
+	}
+	records, err := pageRecords(cgm.partitionInfo(ctx), nil, ManufacturingData, 0, v)
+	if err != nil {
+		return "", err
+	}
+	if len(records) == 0 {
+		return "", fmt.Errorf("no manufacturing data")
+	}
+	serial := records[0].XML()["SerialNumber"]
+	if serial == "" || serial != filepath.Base(serial) || strings.HasPrefix(serial, ".") {
+		return "", fmt.Errorf("invalid serial number %q", serial)
+	}
+	return serial, nil
+}
+
+func cacheFileName(dir string, pageType PageType, pageNumber int) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.%d.data", pageType, pageNumber))
+}
+
+// setRange records the range of pages of the given type on the receiver.
+func (c *pageCache) setRange(pageType PageType, first, last int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ranges[pageType] = pageRange{first: first, last: last}
+}
+
+// pageRange returns the range of pages of the given type on the receiver,
+// reading it if it is not yet known.
+func (c *pageCache) pageRange(ctx context.Context, cgm *CGM, pageType PageType) (pageRange, bool) {
+	c.mu.Lock()
+	r, known := c.ranges[pageType]
+	c.mu.Unlock()
+	if known {
+		return r, true
+	}
+	first, last, err := cgm.readPageRange(ctx, pageType)
+	if err != nil {
+		return pageRange{}, false
+	}
+	return pageRange{first: first, last: last}, true
+}
+
+// get returns the cached page, or nil if it is not in the cache,
+// it is not a full page in the receiver's current page range,
+// or its header is invalid.
+func (c *pageCache) get(ctx context.Context, cgm *CGM, pageType PageType, pageNumber int) []byte {
+	r, known := c.pageRange(ctx, cgm, pageType)
+	if !known || !r.full(pageNumber) {
+		return nil
+	}
+	dir := c.receiverDir(ctx, cgm)
+	if dir == "" {
+		return nil
+	}
+	text, err := ioutil.ReadFile(cacheFileName(dir, pageType, pageNumber))
+	if err != nil {
+		return nil
+	}
+	v, err := hex.DecodeString(strings.Join(strings.Fields(string(text)), ""))
+	if err != nil {
+		return nil
+	}
+	page, err := UnmarshalPageHeader(v)
+	if err != nil || page.Type != pageType || page.Number != pageNumber {
+		return nil
+	}
+	return v
+}
+
+// put stores a page in the cache if it is known to be full.
+func (c *pageCache) put(ctx context.Context, cgm *CGM, pageType PageType, pageNumber int, v []byte) {
+	c.mu.Lock()
+	r, known := c.ranges[pageType]
+	c.mu.Unlock()
+	if !known || !r.full(pageNumber) {
+		return
+	}
+	dir := c.receiverDir(ctx, cgm)
+	if dir == "" {
+		return
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err == nil {
+		err = ioutil.WriteFile(cacheFileName(dir, pageType, pageNumber), []byte(fmt.Sprintf("% X\n", v)), 0644)
+	}
+	if err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,91 @@
+package dexcom
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPageCache(t *testing.T) {
+	const first, count = 310, 5
+	t0 := parseTime("2017-09-17 11:00:00")
+	e := NewEmulator()
+	v, err := readPageFile(testFileName(pageTestCase{ManufacturingData, 0, 0}) + ".data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.AddPage(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := first; n < first+count; n++ {
+		k := n - first
+		err := e.AddPage(makePage(EGVData, n, testRecord(t0.Add(time.Duration(k)*5*time.Minute), &EGVInfo{Glucose: uint16(100 + k), Trend: Flat})))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, err := ioutil.TempDir("", "pagecache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sync := func(want int) {
+		conn := &countingConn{Emulator: e}
+		cgm := &CGM{Connection: conn}
+		cgm.SetPageCache(dir)
+		records := cgm.ReadHistory(EGVData, time.Time{})
+		if cgm.Error() != nil {
+			t.Fatal(cgm.Error())
+		}
+		if len(records) != count {
+			t.Fatalf("ReadHistory returned %d records, want %d", len(records), count)
+		}
+		for i, r := range records {
+			if r.Glucose() != uint16(100+count-1-i) {
+				t.Errorf("record %d has glucose %d, want %d", i, r.Glucose(), 100+count-1-i)
+			}
+		}
+		// One request is for the manufacturing data page.
+		if conn.pageReads-1 != want {
+			t.Errorf("ReadHistory used %d page requests, want %d", conn.pageReads-1, want)
+		}
+	}
+	sync(1 + (count-1+maxPagesPerRead-1)/maxPagesPerRead)
+	sync(1)
+	files, err := filepath.Glob(filepath.Join(dir, "SM44792675", "*.data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != count-1 {
+		t.Errorf("cache contains %d pages, want %d", len(files), count-1)
+	}
+	// A corrupted page is read again.
+	err = ioutil.WriteFile(cacheFileName(filepath.Join(dir, "SM44792675"), EGVData, first), []byte("00 01 02\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sync(2)
+	sync(1)
+	// After the database is erased, page numbers restart,
+	// and pages from the old database are not used.
+	e = NewEmulator()
+	err = e.AddPage(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 2; n++ {
+		err := e.AddPage(makePage(EGVData, n, testRecord(t0.Add(time.Duration(n)*5*time.Minute), &EGVInfo{Glucose: uint16(200 + n), Trend: Flat})))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cgm := &CGM{Connection: e}
+	cgm.SetPageCache(dir)
+	records, err := cgm.ReadRecordsE(EGVData, first)
+	if err == nil {
+		t.Errorf("ReadRecordsE of page %d after erasing returned %v, want error", first, records)
+	}
+}
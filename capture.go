@@ -0,0 +1,222 @@
+package dexcom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Session files contain one line per Send or Receive call,
+// with the data in hexadecimal:
+//
+//	> 01 06 00 0A 5E 65
+//	< 01 06 00 01 35 D4
+//
+// Lines beginning with "#" are comments.
+const (
+	sendPrefix    = '>'
+	receivePrefix = '<'
+	commentPrefix = '#'
+
+	drainComment = "drain"
+)
+
+const (
+	captureEnvVar = "DEXCOM_CAPTURE"
+	replayEnvVar  = "DEXCOM_REPLAY"
+)
+
+// Recorder is a Connection that copies all data sent and received
+// over another Connection to a session log.
+type Recorder struct {
+	conn Connection
+	w    io.Writer
+}
+
+// NewRecorder returns a Recorder that logs the traffic on conn to w.
+// If w is an io.Closer, it is closed when the Recorder is closed.
+func NewRecorder(conn Connection, w io.Writer) *Recorder {
+	return &Recorder{conn: conn, w: w}
+}
+
+// OpenCapture returns a Recorder that logs the traffic on conn to the given file.
+func OpenCapture(conn Connection, file string) (*Recorder, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(conn, f), nil
+}
+
+func (r *Recorder) record(prefix byte, data []byte, err error) {
+	var e error
+	if err != nil {
+		_, e = fmt.Fprintf(r.w, "%c %v\n", commentPrefix, err)
+	} else {
+		_, e = fmt.Fprintf(r.w, "%c % X\n", prefix, data)
+	}
+	if e != nil {
+		log.Print(e)
+	}
+}
+
+// Send writes data over the underlying connection and records it.
+func (r *Recorder) Send(data []byte) error {
+	err := r.conn.Send(data)
+	r.record(sendPrefix, data, err)
+	return err
+}
+
+// Receive reads data from the underlying connection and records it.
+func (r *Recorder) Receive(data []byte) error {
+	err := r.conn.Receive(data)
+	r.record(receivePrefix, data, err)
+	return err
+}
+
+// Drain discards any data waiting to be received over the underlying connection,
+// if it supports draining, and records that in the session log.
+func (r *Recorder) Drain() {
+	d, ok := r.conn.(drainer)
+	if !ok {
+		return
+	}
+	d.Drain()
+	_, err := fmt.Fprintf(r.w, "%c %s\n", commentPrefix, drainComment)
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+// SetDeadline sets the deadline of the underlying connection,
+// if it supports deadlines.
+func (r *Recorder) SetDeadline(t time.Time) error {
+	d, ok := r.conn.(deadliner)
+	if !ok {
+		return fmt.Errorf("connection does not support deadlines")
+	}
+	return d.SetDeadline(t)
+}
+
+// Close closes the underlying connection and the session log.
+func (r *Recorder) Close() {
+	r.conn.Close()
+	if c, ok := r.w.(io.Closer); ok {
+		err := c.Close()
+		if err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// Replayer is a Connection that plays back a session log
+// written by a Recorder.
+// It returns an error if the data sent to it differs from the recorded session.
+type Replayer struct {
+	events  []sessionEvent
+	pending []byte
+}
+
+type sessionEvent struct {
+	send  bool
+	drain bool
+	data  []byte
+}
+
+// NewReplayer reads a session log and returns a Replayer for it.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	var events []sessionEvent
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if len(text) == 0 {
+			continue
+		}
+		if text[0] == commentPrefix {
+			if strings.TrimSpace(text[1:]) == drainComment {
+				events = append(events, sessionEvent{drain: true})
+			}
+			continue
+		}
+		if text[0] != sendPrefix && text[0] != receivePrefix {
+			return nil, fmt.Errorf("session line %d: unexpected prefix %q", line, text[0])
+		}
+		data, err := hex.DecodeString(strings.Replace(text[1:], " ", "", -1))
+		if err != nil {
+			return nil, fmt.Errorf("session line %d: %v", line, err)
+		}
+		events = append(events, sessionEvent{send: text[0] == sendPrefix, data: data})
+	}
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return &Replayer{events: events}, nil
+}
+
+// OpenReplay returns a Replayer for the given session file.
+func OpenReplay(file string) (*Replayer, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplayer(f)
+}
+
+// Send checks that data matches the next request in the session.
+func (r *Replayer) Send(data []byte) error {
+	if len(r.pending) != 0 {
+		return fmt.Errorf("replay: sending % X with %d recorded response bytes unread", data, len(r.pending))
+	}
+	if len(r.events) == 0 {
+		return fmt.Errorf("replay: sending % X after end of session", data)
+	}
+	e := r.events[0]
+	if e.drain {
+		return fmt.Errorf("replay: sending % X when session expects a drain", data)
+	}
+	if !e.send {
+		return fmt.Errorf("replay: sending % X when session expects a response", data)
+	}
+	if !bytes.Equal(data, e.data) {
+		return fmt.Errorf("replay: sending % X, session expects % X", data, e.data)
+	}
+	r.events = r.events[1:]
+	return nil
+}
+
+// Receive returns the next recorded response data from the session.
+func (r *Replayer) Receive(data []byte) error {
+	for len(r.pending) < len(data) {
+		if len(r.events) == 0 || r.events[0].send || r.events[0].drain {
+			return fmt.Errorf("replay: receiving %d bytes, session has %d", len(data), len(r.pending))
+		}
+		r.pending = append(r.pending, r.events[0].data...)
+		r.events = r.events[1:]
+	}
+	n := copy(data, r.pending)
+	r.pending = r.pending[n:]
+	return nil
+}
+
+// Drain discards recorded response data up to the point
+// where the session was drained when it was recorded.
+func (r *Replayer) Drain() {
+	r.pending = nil
+	for len(r.events) != 0 && !r.events[0].send {
+		drain := r.events[0].drain
+		r.events = r.events[1:]
+		if drain {
+			return
+		}
+	}
+}
+
+// Close is a no-op for a Replayer.
+func (r *Replayer) Close() {}
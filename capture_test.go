@@ -0,0 +1,52 @@
+package dexcom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCaptureReplay(t *testing.T) {
+	var session bytes.Buffer
+	cgm := &CGM{Connection: NewRecorder(newTestEmulator(t), &session)}
+	want := cgm.ReadRecords(EGVData, 312)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	cgm.Close()
+	r, err := NewReplayer(&session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cgm = &CGM{Connection: r}
+	got := cgm.ReadRecords(EGVData, 312)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("replay returned %d records, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].String() != want[i].String() {
+			t.Errorf("replayed record %d == %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReplayDivergence(t *testing.T) {
+	var session bytes.Buffer
+	cgm := &CGM{Connection: NewRecorder(newTestEmulator(t), &session)}
+	cgm.ReadPageRange(EGVData)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	r, err := NewReplayer(&session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cgm = &CGM{Connection: r}
+	cgm.ReadPageRange(SensorData)
+	if cgm.Error() == nil || !strings.HasPrefix(cgm.Error().Error(), "replay:") {
+		t.Errorf("ReadPageRange(SensorData) returned %v, want replay error", cgm.Error())
+	}
+}
@@ -6,6 +6,11 @@ Based on the Python version at github.com/bewest/decoding-dexcom
 */
 package dexcom
 
+import (
+	"os"
+	"sync"
+)
+
 // Connection is the interface satisfied by a CGM connection.
 type Connection interface {
 	Send([]byte) error
@@ -14,28 +19,94 @@ type Connection interface {
 }
 
 // CGM represents a CGM connection.
+// Command exchanges are serialized: each exchange with the receiver
+// completes before the next one starts.
+//
+// The methods that use the CGM's error state (those that check Error
+// before proceeding and set it afterward, including the Context variants)
+// are meant to be used by a single goroutine, since each of them
+// replaces the shared error state when it completes.
+// Concurrent callers must use the methods whose names end in E,
+// which return errors directly and leave the error state alone.
 type CGM struct {
 	Connection
-	err error
+	semOnce sync.Once
+	sem     chan struct{} // 1-slot semaphore that serializes exchanges
+	errMu   sync.Mutex    // protects err
+	err     error
+	retryMu sync.Mutex // protects retry
+	retry   RetryPolicy
+
+	infoMu   sync.Mutex // protects info, infoRead, and skipped
+	info     *PartitionInfo
+	infoRead bool
+	skipped  CRCErrorFunc
+
+	cacheMu sync.Mutex // protects cache
+	cache   *pageCache
 }
 
 // Open first attempts to open a USB connection;
 // if that fails it tries a BLE connection.
+//
+// If the DEXCOM_REPLAY environment variable is set,
+// the session in the named file is replayed instead.
+// If the DEXCOM_CAPTURE environment variable is set,
+// the session is recorded in the named file.
 func Open() *CGM {
-	conn, err := OpenUSB()
-	if err == nil {
+	if file := os.Getenv(replayEnvVar); file != "" {
+		conn, err := OpenReplay(file)
+		if err != nil {
+			return &CGM{err: err}
+		}
 		return &CGM{Connection: conn}
 	}
-	conn, err = OpenBLE()
-	return &CGM{Connection: conn, err: err}
+	conn, err := OpenUSB()
+	if err != nil {
+		conn, err = OpenBLE()
+	}
+	if err != nil {
+		return &CGM{Connection: conn, err: err}
+	}
+	if file := os.Getenv(captureEnvVar); file != "" {
+		rec, err := OpenCapture(conn, file)
+		if err != nil {
+			conn.Close()
+			return &CGM{err: err}
+		}
+		conn = rec
+	}
+	return &CGM{Connection: conn}
+}
+
+// SetLenient sets whether records with invalid CRCs are skipped
+// when reading records, rather than causing an error.
+// If skipped is non-nil, it is called for each skipped record;
+// if it is nil, lenient mode is disabled.
+func (cgm *CGM) SetLenient(skipped CRCErrorFunc) {
+	cgm.infoMu.Lock()
+	cgm.skipped = skipped
+	cgm.infoMu.Unlock()
+}
+
+func (cgm *CGM) lenient() CRCErrorFunc {
+	cgm.infoMu.Lock()
+	defer cgm.infoMu.Unlock()
+	return cgm.skipped
 }
 
 // Error returns the error state of the CGM.
 func (cgm *CGM) Error() error {
+	cgm.errMu.Lock()
+	defer cgm.errMu.Unlock()
 	return cgm.err
 }
 
 // SetError sets the error state of the CGM.
+// Like the other methods that use the error state,
+// it is meant to be used by a single goroutine.
 func (cgm *CGM) SetError(err error) {
+	cgm.errMu.Lock()
+	defer cgm.errMu.Unlock()
 	cgm.err = err
 }
@@ -0,0 +1,109 @@
+package dexcom
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentCommands(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	cases := []struct {
+		pageType    PageType
+		first, last int
+	}{
+		{ManufacturingData, 0, 0},
+		{SensorData, 469, 469},
+		{EGVData, 312, 312},
+		{CalibrationData, 1432, 1432},
+		{UserEventData, -1, -1},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, c := range cases {
+			wg.Add(1)
+			go func(pageType PageType, first, last int) {
+				defer wg.Done()
+				v, err := cgm.CmdE(ReadDatabasePageRange, byte(pageType))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				f, l := int(unmarshalInt32(v[:4])), int(unmarshalInt32(v[4:]))
+				if f != first || l != last {
+					t.Errorf("ReadDatabasePageRange(%v) == (%d, %d), want (%d, %d)", pageType, f, l, first, last)
+				}
+			}(c.pageType, c.first, c.last)
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := cgm.CmdE(Command(0xFE))
+		if err == nil {
+			t.Errorf("CmdE(FE) succeeded, want error")
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		records := cgm.ReadHistory(EGVData, time.Time{})
+		if len(records) == 0 {
+			t.Errorf("ReadHistory returned no records")
+		}
+	}()
+	wg.Wait()
+	if cgm.Error() != nil {
+		t.Errorf("CGM error state == %v, want nil", cgm.Error())
+	}
+}
+
+func TestErrorReturningAPI(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	first, last, err := cgm.ReadPageRangeE(EGVData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != 312 || last != 312 {
+		t.Errorf("ReadPageRangeE(EGVData) == (%d, %d), want (312, 312)", first, last)
+	}
+	_, err = cgm.ReadRecordsE(EGVData, 313)
+	if !IsResponseCode(err, InvalidParam) {
+		t.Errorf("ReadRecordsE(EGVData, 313) returned %v, want InvalidParam response", err)
+	}
+	if cgm.Error() != nil {
+		t.Errorf("CGM error state == %v, want nil", cgm.Error())
+	}
+	records, err := cgm.ReadCountE(SensorData, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Errorf("ReadCountE(SensorData, 5) returned %d records", len(records))
+	}
+	x, err := cgm.ReadXMLRecordE(ManufacturingData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.XML()["SerialNumber"] != "SM44792675" {
+		t.Errorf("ReadXMLRecordE(ManufacturingData) == %v", x)
+	}
+}
+
+func TestStickyError(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	cgm.ReadRecords(EGVData, 313)
+	err := cgm.Error()
+	if err == nil {
+		t.Fatal("ReadRecords(EGVData, 313) succeeded, want error")
+	}
+	first, last := cgm.ReadPageRange(EGVData)
+	if first != -1 || last != -1 || cgm.Error().Error() != err.Error() {
+		t.Errorf("ReadPageRange after error == (%d, %d) with error %v, want (-1, -1) with %v", first, last, cgm.Error(), err)
+	}
+	cgm.SetError(nil)
+	first, last = cgm.ReadPageRange(EGVData)
+	if cgm.Error() != nil || first != 312 || last != 312 {
+		t.Errorf("ReadPageRange after reset == (%d, %d) with error %v", first, last, cgm.Error())
+	}
+}
@@ -67,7 +67,8 @@ func findGaps() ([]nightscout.Gap, time.Time) {
 }
 
 func getRecords(cutoff time.Time) nightscout.Entries {
-	cgm := dexcom.Open()
+	// Read from archived pages if any files are given.
+	cgm := dexcom.OpenSource(flag.Args()...)
 	if cgm.Error() != nil {
 		log.Fatal(cgm.Error())
 	}
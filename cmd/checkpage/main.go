@@ -12,7 +12,8 @@ import (
 )
 
 var (
-	nsFlag = flag.Bool("n", false, "print records in Nightscout format")
+	nsFlag      = flag.Bool("n", false, "print records in Nightscout format")
+	lenientFlag = flag.Bool("l", false, "skip records with invalid CRCs")
 )
 
 func main() {
@@ -32,7 +33,17 @@ func main() {
 }
 
 func readRecords(data []byte) {
-	page, err := dexcom.UnmarshalPage(data)
+	var page *dexcom.PageInfo
+	var err error
+	if *lenientFlag {
+		var crcErrors []dexcom.CRCError
+		page, crcErrors, err = dexcom.UnmarshalPageLenient(data)
+		for _, e := range crcErrors {
+			log.Print(e)
+		}
+	} else {
+		page, err = dexcom.UnmarshalPage(data)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -13,7 +13,17 @@ func main() {
 		log.Fatal(cgm.Error())
 	}
 	fmt.Println("display time:", cgm.ReadDisplayTime())
-	fmt.Println("transmitter ID:", string(cgm.Cmd(dexcom.ReadTransmitterID)))
+	fmt.Println("transmitter ID:", cgm.ReadTransmitterID())
+	fmt.Println("language:", cgm.ReadLanguage())
+	fmt.Println("glucose units:", cgm.ReadGlucoseUnits())
+	fmt.Println("clock mode:", cgm.ReadClockMode())
+	fmt.Println("blinded mode:", cgm.ReadBlindMode())
+	fmt.Printf("battery: %d%% (%v)\n", cgm.ReadBatteryLevel(), cgm.ReadBatteryState())
+	fmt.Println("charger current:", cgm.ReadChargerCurrentSetting())
+	fmt.Println("hardware ID:", cgm.ReadHardwareID())
+	if cgm.Error() != nil {
+		log.Fatal(cgm.Error())
+	}
 	printXMLInfo("firmware header", cgm.ReadFirmwareHeader())
 	printXMLRecord(cgm, dexcom.ManufacturingData, "manufacturing data")
 	printXMLRecord(cgm, dexcom.SoftwareData, "PC software parameter")
@@ -28,7 +38,10 @@ func printXMLInfo(name string, xml dexcom.XMLInfo) {
 
 func printXMLRecord(cgm *dexcom.CGM, pageType dexcom.PageType, description string) {
 	r := cgm.ReadXMLRecord(pageType)
-	xml := r.Info.(dexcom.XMLInfo)
+	xml := r.XML()
+	if xml == nil {
+		log.Fatalf("%s: unexpected %v record", description, r.PageType())
+	}
 	printXMLInfo(description, xml)
 	fmt.Printf("    %+v\n", r.Timestamp)
 }
@@ -0,0 +1,65 @@
+package main
+
+// Save a complete copy of a Dexcom G4 receiver's database to a file.
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ecc1/dexcom"
+)
+
+var (
+	listFlag = flag.Bool("l", false, "list the contents of an existing archive instead of creating one")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [options] file\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	file := flag.Arg(0)
+	if *listFlag {
+		a, err := dexcom.LoadArchive(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		list(a)
+		return
+	}
+	cgm := dexcom.Open()
+	a := cgm.Backup()
+	if cgm.Error() != nil {
+		log.Fatal(cgm.Error())
+	}
+	err := a.Save(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	list(a)
+}
+
+func list(a *dexcom.Archive) {
+	fmt.Printf("created:        %s\n", a.Created.Format(dexcom.UserTimeLayout))
+	fmt.Printf("system time:    %s\n", a.SystemTime.Format(dexcom.UserTimeLayout))
+	fmt.Printf("display time:   %s\n", a.DisplayTime.Format(dexcom.UserTimeLayout))
+	fmt.Printf("firmware:       %s\n", a.FirmwareHeader["FirmwareVersion"])
+	fmt.Printf("hardware ID:    %d\n", a.HardwareID)
+	fmt.Printf("transmitter ID: %s\n", a.TransmitterID)
+	for p := dexcom.FirstPageType; p <= dexcom.LastPageType; p++ {
+		first, last := a.PageRange(p)
+		if first < 0 {
+			continue
+		}
+		fmt.Printf("%-18v pages %d to %d\n", p, first, last)
+	}
+}
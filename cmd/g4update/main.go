@@ -33,8 +33,10 @@ var (
 	verboseFlag        = flag.Bool("v", false, "verbose mode")
 	jsonFile           = flag.String("f", "", "append results to JSON `file`")
 	jsonCutoff         = flag.Duration("k", 7*24*time.Hour, "maximum age of CGM entries to keep in JSON file")
+	attemptsFlag       = flag.Int("n", 3, "number of `attempts` for each receiver command")
+	cacheFlag          = flag.String("c", "", "cache database pages in `directory`")
 
-	cgm        *dexcom.CGM
+	cgm        dexcom.Source
 	cgmTime    time.Time
 	cgmEpoch   time.Time
 	glucose    dexcom.Records
@@ -42,6 +44,8 @@ var (
 	oldEntries Entries
 	newEntries Entries
 
+	newTreatments []dexcom.NightscoutTreatment
+
 	somethingFailed = false
 )
 
@@ -65,6 +69,7 @@ func main() {
 	}
 	if *uploadFlag {
 		uploadEntries()
+		uploadTreatments()
 	}
 	if somethingFailed {
 		os.Exit(1)
@@ -72,8 +77,15 @@ func main() {
 }
 
 func getCGMInfo() {
-	cgm = dexcom.Open()
-	cgmTime = checkCGMClock()
+	// Read from archived pages if any files are given.
+	cgm = dexcom.OpenSource(flag.Args()...)
+	if r, ok := cgm.(*dexcom.CGM); ok {
+		r.SetRetryPolicy(dexcom.RetryPolicy{Attempts: *attemptsFlag})
+		r.SetPageCache(*cacheFlag)
+		cgmTime = checkCGMClock()
+	} else {
+		cgmTime = cgm.ReadDisplayTime()
+	}
 	if cgm.Error() != nil {
 		log.Fatal(cgm.Error())
 	}
@@ -99,6 +111,7 @@ func getCGMInfo() {
 	egv := cgm.ReadHistory(dexcom.EGVData, cutoff)
 	meter := cgm.ReadHistory(dexcom.MeterData, cutoff)
 	cal := cgm.ReadHistory(dexcom.CalibrationData, cutoff)
+	events := cgm.ReadHistory(dexcom.UserEventData, cutoff)
 	if cgm.Error() != nil {
 		log.Fatal(cgm.Error())
 	}
@@ -110,6 +123,13 @@ func getCGMInfo() {
 	log.Printf("%d CGM records", len(cgmRecords))
 	newEntries = discardIncomplete(dexcom.NightscoutEntries(cgmRecords))
 	describeEntries(newEntries, "Nightscout")
+	var skipped dexcom.Records
+	newTreatments, skipped = dexcom.NightscoutTreatments(events)
+	for _, r := range skipped {
+		e := r.Info.(*dexcom.UserEventInfo)
+		log.Printf("skipping %v user event at %s", e.Type, e.EventTime.Format(dexcom.UserTimeLayout))
+	}
+	log.Printf("%d user event treatments", len(newTreatments))
 }
 
 func timeStr(e nightscout.Entry) string {
@@ -164,6 +184,23 @@ func uploadEntries() {
 	}
 }
 
+// Nightscout matches treatments by time and event type,
+// so uploading the same user event again does not duplicate it.
+func uploadTreatments() {
+	if len(newTreatments) == 0 {
+		return
+	}
+	log.Printf("uploading %d treatments to Nightscout", len(newTreatments))
+	for _, t := range newTreatments {
+		err := nightscout.Upload("POST", "treatments", t)
+		if err != nil {
+			log.Print(err)
+			somethingFailed = true
+			return
+		}
+	}
+}
+
 // If the most recent glucose entry is incomplete, discard it.
 // This can happen if the loop runs at the same time the sensor
 // transmits a new reading, or if the sensor is warming up. If we
@@ -50,10 +50,15 @@ func main() {
 	}
 	var cutoff time.Time
 	var err error
-	cgm := dexcom.Open()
+	// Read from archived pages if any files are given.
+	cgm := dexcom.OpenSource(flag.Args()...)
 	if cgm.Error() != nil {
 		log.Fatal(cgm.Error())
 	}
+	now := time.Now()
+	if flag.NArg() != 0 {
+		now = cgm.ReadDisplayTime()
+	}
 	if *all {
 		log.Printf("retrieving entire record history")
 		*egv = true
@@ -66,7 +71,7 @@ func main() {
 			log.Fatal(err)
 		}
 	} else {
-		cutoff = time.Now().Add(-*duration)
+		cutoff = now.Add(-*duration)
 	}
 	if !*all {
 		log.Printf("retrieving records since %s", cutoff.Format(dexcom.UserTimeLayout))
@@ -92,7 +97,7 @@ func main() {
 	}
 }
 
-func scanRecords(cgm *dexcom.CGM, cutoff time.Time) []dexcom.Records {
+func scanRecords(cgm dexcom.Source, cutoff time.Time) []dexcom.Records {
 	var scans []dexcom.Records
 	for _, t := range recordTypes {
 		if !*t.flag {
@@ -121,20 +126,20 @@ func printJSON(v interface{}) {
 func printRecord(r dexcom.Record) {
 	t := r.Time().Format(dexcom.UserTimeLayout)
 	switch info := r.Info.(type) {
-	case dexcom.SensorInfo:
+	case *dexcom.SensorInfo:
 		printSensor(t, info)
-	case dexcom.EGVInfo:
+	case *dexcom.EGVInfo:
 		printEGV(t, info)
-	case dexcom.CalibrationInfo:
+	case *dexcom.CalibrationInfo:
 		printCalibration(t, info)
-	case dexcom.MeterInfo:
+	case *dexcom.MeterInfo:
 		printMeter(t, info)
 	default:
 		panic(fmt.Sprintf("unexpected record %+v", r))
 	}
 }
 
-func printSensor(t string, s dexcom.SensorInfo) {
+func printSensor(t string, s *dexcom.SensorInfo) {
 	switch *format {
 	case csvFormat:
 		fmt.Printf("%s,G,,%d\n", t, s.Unfiltered)
@@ -143,7 +148,7 @@ func printSensor(t string, s dexcom.SensorInfo) {
 	}
 }
 
-func printEGV(t string, e dexcom.EGVInfo) {
+func printEGV(t string, e *dexcom.EGVInfo) {
 	switch *format {
 	case csvFormat:
 		fmt.Printf("%s,G,%d,\n", t, e.Glucose)
@@ -152,7 +157,7 @@ func printEGV(t string, e dexcom.EGVInfo) {
 	}
 }
 
-func printCalibration(t string, cal dexcom.CalibrationInfo) {
+func printCalibration(t string, cal *dexcom.CalibrationInfo) {
 	switch *format {
 	case csvFormat:
 		fmt.Printf("%s,%s,,,%g,%g,%g,%g\n", t, "C", cal.Slope, cal.Intercept, cal.Scale, cal.Decay)
@@ -169,7 +174,7 @@ func printCalibration(t string, cal dexcom.CalibrationInfo) {
 	}
 }
 
-func printMeter(t string, m dexcom.MeterInfo) {
+func printMeter(t string, m *dexcom.MeterInfo) {
 	switch *format {
 	case csvFormat:
 		fmt.Printf("%s,%s,%d\n", t, "M", m.Glucose)
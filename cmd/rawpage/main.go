@@ -12,6 +12,7 @@ import (
 var (
 	pageNumber   = flag.Int("n", -1, "`page` number to read; -1 for most recent")
 	pageTypeFlag = flag.Int("p", int(dexcom.EGVData), "page `type` to read")
+	headerFlag   = flag.Bool("i", false, "print page header information only")
 )
 
 func usage() {
@@ -40,6 +41,13 @@ func main() {
 	if cgm.Error() != nil {
 		log.Fatal(cgm.Error())
 	}
+	if *headerFlag {
+		printHeader(cgm.ReadPageHeader(pageType, pageNum))
+		if cgm.Error() != nil {
+			log.Fatal(cgm.Error())
+		}
+		return
+	}
 	log.Printf("reading %v page %d", pageType, pageNum)
 	v := cgm.ReadPage(pageType, pageNum)
 	if cgm.Error() != nil {
@@ -47,3 +55,12 @@ func main() {
 	}
 	fmt.Printf("% X\n", v)
 }
+
+func printHeader(page *dexcom.PageInfo) {
+	if page == nil {
+		return
+	}
+	fmt.Printf("%v page %d: revision %d, records %d to %d, reserved %v\n",
+		page.Type, page.Number, page.Revision,
+		page.FirstIndex, page.FirstIndex+page.NumRecords-1, page.Reserved)
+}
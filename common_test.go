@@ -43,6 +43,21 @@ func parseTime(s string) time.Time {
 	return t
 }
 
+// testRecord returns a record whose system and display times are both t.
+func testRecord(t time.Time, info RecordInfo) Record {
+	return Record{Timestamp: Timestamp{SystemTime: t, DisplayTime: t}, Info: info}
+}
+
+// makePage returns a page containing the given records,
+// which must be in reverse chronological order.
+func makePage(pageType PageType, pageNumber int, records ...Record) []byte {
+	v, err := MarshalPage(pageType, pageNumber, records)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
 func compareDataToJSON(data interface{}, jsonFile string) (bool, string) {
 	// Write data in JSON format to temporary file.
 	tmpfile, err := ioutil.TempFile("", "json")
@@ -0,0 +1,114 @@
+package dexcom
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// stuckConn is a Connection whose Receive blocks until it is closed,
+// like a USB read from an unresponsive receiver.
+type stuckConn struct {
+	closed chan struct{}
+	sent   chan struct{} // if not nil, receives a value for each Send
+}
+
+func (conn *stuckConn) Send([]byte) error {
+	if conn.sent != nil {
+		conn.sent <- struct{}{}
+	}
+	return nil
+}
+
+func (conn *stuckConn) Receive([]byte) error {
+	<-conn.closed
+	return fmt.Errorf("connection closed")
+}
+
+func (conn *stuckConn) Close() { close(conn.closed) }
+
+func TestCmdContextDeadline(t *testing.T) {
+	conn := &stuckConn{closed: make(chan struct{})}
+	cgm := &CGM{Connection: conn}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cgm.CmdContext(ctx, Ping)
+	if cgm.Error() != context.DeadlineExceeded {
+		t.Errorf("CmdContext returned %v, want %v", cgm.Error(), context.DeadlineExceeded)
+	}
+	select {
+	case <-conn.closed:
+		t.Errorf("connection was closed")
+	default:
+	}
+	conn.Close()
+}
+
+func TestCmdContextDeadlineUSB(t *testing.T) {
+	// The rest of the response does not arrive before the receive timeout,
+	// so the next command can proceed only if the exchange is interrupted.
+	port := newEmulatorPort(newTestEmulator(t), 1, time.Hour)
+	cgm := &CGM{Connection: newUSBConn(port, 2*time.Second)}
+	defer cgm.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	cgm.CmdContext(ctx, Ping)
+	if cgm.Error() != context.DeadlineExceeded {
+		t.Fatalf("CmdContext returned %v, want %v", cgm.Error(), context.DeadlineExceeded)
+	}
+	cgm.SetError(nil)
+	records := cgm.ReadRecords(SensorData, 469)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("commands took %v, want the first one interrupted at its deadline", d)
+	}
+	checkRecords(t, records, testFileName(pageTestCase{SensorData, 469, 0})+".json")
+}
+
+func TestCmdContextWaiting(t *testing.T) {
+	conn := &stuckConn{closed: make(chan struct{}), sent: make(chan struct{}, 1)}
+	cgm := &CGM{Connection: conn}
+	// Hold the connection with an exchange that never completes.
+	stuck := make(chan error, 1)
+	go func() {
+		_, err := cgm.CmdE(Ping)
+		stuck <- err
+	}()
+	<-conn.sent
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cgm.cmd(ctx, Ping, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("cmd waiting for a stuck exchange returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	conn.Close()
+	<-stuck
+}
+
+func TestReadHistoryContextCanceled(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := cgm.ReadHistoryContext(ctx, EGVData, time.Time{})
+	if cgm.Error() != context.Canceled {
+		t.Errorf("ReadHistoryContext returned %v, want %v", cgm.Error(), context.Canceled)
+	}
+	if len(results) != 0 {
+		t.Errorf("ReadHistoryContext returned %d records, want 0", len(results))
+	}
+}
+
+func TestReadHistoryContext(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	results := cgm.ReadHistoryContext(ctx, SensorData, time.Time{})
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	checkRecords(t, results, testFileName(pageTestCase{SensorData, 469, 0})+".json")
+}
@@ -0,0 +1,316 @@
+package dexcom
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Emulator simulates a Dexcom G4 receiver in software.
+// It implements the Connection interface, so a CGM that uses it
+// can be exercised without a physical receiver:
+//
+//	cgm := &CGM{Connection: NewEmulator()}
+type Emulator struct {
+	mu       sync.Mutex
+	settings map[Command][]byte
+	pages    map[PageType]map[int][]byte
+	response bytes.Buffer
+	closed   bool
+}
+
+const (
+	emulatorFirmwareHeader = `<FirmwareHeader SchemaVersion="1" ApiVersion="2.2.0.0" TestApiVersion="2.4.0.0" ProductId="G4Receiver" ProductName="Dexcom G4 Receiver" SoftwareNumber="SW10050" FirmwareVersion="4.0.1.048" PortVersion="4.6.4.45" RFVersion="1.0.0.27" DexBootVersion="3" />`
+
+	emulatorFirmwareSettings = `<FirmwareSettings FirmwareImageId="Emulator" />`
+)
+
+// NewEmulator returns an Emulator with an empty database
+// and default receiver settings.
+// Its real-time clock is set to the current time.
+func NewEmulator() *Emulator {
+	e := &Emulator{
+		settings: make(map[Command][]byte),
+		pages:    make(map[PageType]map[int][]byte),
+	}
+	e.Set(ReadFirmwareHeader, []byte(emulatorFirmwareHeader))
+	e.Set(ReadFirmwareSettings, []byte(emulatorFirmwareSettings))
+	e.Set(ReadTransmitterID, []byte("00000"))
+	e.Set(ReadLanguage, marshalUint16(1033))
+	e.Set(ReadRTC, marshalUint32(uint32(fromTime(time.Now()))))
+	e.Set(ReadSystemTimeOffset, marshalInt32(0))
+	e.Set(ReadDisplayTimeOffset, marshalInt32(0))
+	e.Set(ReadBatteryLevel, marshalUint32(100))
+	e.Set(ReadBatteryState, []byte{2})
+	e.Set(ReadGlucoseUnits, []byte{1})
+	e.Set(ReadBlindMode, []byte{0})
+	e.Set(ReadClockMode, []byte{0})
+	e.Set(ReadDeviceMode, []byte{0})
+	e.Set(ReadHardwareID, []byte{0})
+	e.Set(ReadEnableSetupWizardFlag, []byte{0})
+	e.Set(ReadSetupWizardState, []byte{0})
+	e.Set(ReadChargerCurrentSetting, []byte{0})
+	return e
+}
+
+// Set sets the raw value that the emulator returns for the given read command.
+func (e *Emulator) Set(cmd Command, value []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.set(cmd, value)
+}
+
+func (e *Emulator) set(cmd Command, value []byte) {
+	e.settings[cmd] = append([]byte(nil), value...)
+}
+
+// AddPage adds a raw database page to the emulator.
+// The page type and number are taken from the page header,
+// and a page with the same type and number is replaced.
+func (e *Emulator) AddPage(v []byte) error {
+	page, err := UnmarshalPageHeader(v)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	m := e.pages[page.Type]
+	if m == nil {
+		m = make(map[int][]byte)
+		e.pages[page.Type] = m
+	}
+	m[page.Number] = append([]byte(nil), v...)
+	return nil
+}
+
+// Erase removes all pages from the emulator's database.
+func (e *Emulator) Erase() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.erase()
+}
+
+func (e *Emulator) erase() {
+	e.pages = make(map[PageType]map[int][]byte)
+}
+
+func (e *Emulator) pageRange(pageType PageType) (int, int) {
+	m := e.pages[pageType]
+	if len(m) == 0 {
+		return -1, -1
+	}
+	first, last := -1, -1
+	for n := range m {
+		if first == -1 || n < first {
+			first = n
+		}
+		if last == -1 || n > last {
+			last = n
+		}
+	}
+	return first, last
+}
+
+// Send processes a packet sent to the emulator
+// and queues the receiver's response.
+func (e *Emulator) Send(data []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return fmt.Errorf("emulator is closed")
+	}
+	rc, resp := e.process(data)
+	e.response.Write(marshalPacket(rc, resp))
+	return nil
+}
+
+// Receive reads queued response data from the emulator.
+func (e *Emulator) Receive(data []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return fmt.Errorf("emulator is closed")
+	}
+	if e.response.Len() < len(data) {
+		return fmt.Errorf("emulator has %d bytes available, %d requested", e.response.Len(), len(data))
+	}
+	_, err := e.response.Read(data)
+	return err
+}
+
+// Drain discards any queued response data.
+func (e *Emulator) Drain() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.response.Reset()
+}
+
+// Close closes the emulator.
+func (e *Emulator) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+	e.response.Reset()
+}
+
+// Write commands and the read commands whose values they set.
+var emulatorWrites = map[Command]Command{
+	WriteTransmitterID:         ReadTransmitterID,
+	WriteLanguage:              ReadLanguage,
+	WriteDisplayTimeOffset:     ReadDisplayTimeOffset,
+	WriteGlucoseUnits:          ReadGlucoseUnits,
+	WriteBlindMode:             ReadBlindMode,
+	WriteClockMode:             ReadClockMode,
+	WriteChargerCurrentSetting: ReadChargerCurrentSetting,
+}
+
+// process decodes a packet and returns the response code and data.
+func (e *Emulator) process(pkt []byte) (Command, []byte) {
+	if len(pkt) < minPacket || len(pkt) > maxPacket {
+		return IncompletePacketReceived, nil
+	}
+	if pkt[0] != startOfMessage || int(unmarshalUint16(pkt[1:3])) != len(pkt) {
+		return IncompletePacketReceived, nil
+	}
+	n := len(pkt) - 2
+	if unmarshalUint16(pkt[n:]) != crc16(pkt[:n]) {
+		return Nak, nil
+	}
+	cmd := Command(pkt[3])
+	params := pkt[4:n]
+	switch cmd {
+	case Ping, ResetReceiver, ShutdownReceiver:
+		return Ack, nil
+	case WriteSoftwareParameters:
+		if len(params) == 0 || len(params) > maxSoftwareParameters {
+			return InvalidParam, nil
+		}
+		err := e.appendRecord(SoftwareData, SoftwareInfo(umarshalXMLBytes(params)))
+		if err != nil {
+			return InvalidParam, nil
+		}
+		return Ack, nil
+	case EraseDatabase:
+		e.erase()
+		return Ack, nil
+	case ReadDatabasePartitionInfo:
+		return Ack, e.partitionInfo()
+	case ReadDatabasePageRange:
+		if len(params) != 1 {
+			return InvalidParam, nil
+		}
+		first, last := e.pageRange(PageType(params[0]))
+		return Ack, append(marshalInt32(int32(first)), marshalInt32(int32(last))...)
+	case ReadDatabasePages:
+		if len(params) != 6 {
+			return InvalidParam, nil
+		}
+		return e.readPages(PageType(params[0]), int(unmarshalInt32(params[1:5])), int(params[5]))
+	case ReadDatabasePageHeader:
+		if len(params) != 5 {
+			return InvalidParam, nil
+		}
+		rc, v := e.readPages(PageType(params[0]), int(unmarshalInt32(params[1:5])), 1)
+		if rc != Ack {
+			return rc, nil
+		}
+		return Ack, v[:headerSize]
+	case ReadSystemTime:
+		return Ack, marshalUint32(e.systemTime())
+	case WriteSystemTime:
+		if len(params) != 4 {
+			return InvalidParam, nil
+		}
+		rtc := unmarshalUint32(e.settings[ReadRTC])
+		offset := int32(int64(unmarshalUint32(params)) - int64(rtc))
+		e.set(ReadSystemTimeOffset, marshalInt32(offset))
+		return Ack, nil
+	}
+	if r, found := emulatorWrites[cmd]; found {
+		if len(params) != len(e.settings[r]) {
+			return InvalidParam, nil
+		}
+		e.set(r, params)
+		return Ack, nil
+	}
+	if v, found := e.settings[cmd]; found {
+		if len(params) != 0 {
+			return InvalidParam, nil
+		}
+		return Ack, v
+	}
+	return InvalidCommand, nil
+}
+
+func (e *Emulator) systemTime() uint32 {
+	rtc := unmarshalUint32(e.settings[ReadRTC])
+	offset := unmarshalInt32(e.settings[ReadSystemTimeOffset])
+	return uint32(int64(rtc) + int64(offset))
+}
+
+// appendRecord stores a record, time-stamped with the current
+// system and display time, in a new page of the given type.
+func (e *Emulator) appendRecord(pageType PageType, info RecordInfo) error {
+	sys := e.systemTime()
+	r := Record{
+		Timestamp: Timestamp{
+			SystemTime:  toTime(int64(sys)),
+			DisplayTime: displayTime(sys, unmarshalInt32(e.settings[ReadDisplayTimeOffset])),
+		},
+		Info: info,
+	}
+	_, last := e.pageRange(pageType)
+	v, err := MarshalPage(pageType, last+1, Records{r})
+	if err != nil {
+		return err
+	}
+	m := e.pages[pageType]
+	if m == nil {
+		m = make(map[int][]byte)
+		e.pages[pageType] = m
+	}
+	m[last+1] = v
+	return nil
+}
+
+func (e *Emulator) readPages(pageType PageType, first int, count int) (Command, []byte) {
+	m := e.pages[pageType]
+	if count == 0 || m == nil {
+		return InvalidParam, nil
+	}
+	var buf bytes.Buffer
+	for n := first; n < first+count; n++ {
+		v, found := m[n]
+		if !found {
+			return InvalidParam, nil
+		}
+		buf.Write(v)
+	}
+	if buf.Len() > maxPacket-minPacket {
+		return InvalidParam, nil
+	}
+	return Ack, buf.Bytes()
+}
+
+func (e *Emulator) partitionInfo() []byte {
+	types := make([]int, 0, len(recordLength))
+	for t := range recordLength {
+		types = append(types, int(t))
+	}
+	sort.Ints(types)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<PartitionInfo SchemaVersion="1" PageHeaderVersion="1" PageDataLength="%d">`, pageDataSize)
+	for _, t := range types {
+		pageType := PageType(t)
+		n := recordLength[pageType]
+		if n == 0 {
+			n = pageDataSize
+		}
+		rev := pageRevision(pageType)
+		fmt.Fprintf(&buf, `<Partition Name="%v" Id="%d" RecordRevision="%d" RecordLength="%d" />`, pageType, t, rev, n)
+	}
+	buf.WriteString(`</PartitionInfo>`)
+	return buf.Bytes()
+}
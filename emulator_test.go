@@ -0,0 +1,136 @@
+package dexcom
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// Pages in testdata that can be loaded into an emulator together.
+var emulatorPages = []pageTestCase{
+	{ManufacturingData, 0, 0},
+	{SensorData, 469, 0},
+	{EGVData, 312, 0},
+	{CalibrationData, 1432, 0},
+}
+
+func readPageFile(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readBytes(f)
+}
+
+func newTestEmulator(t *testing.T) *Emulator {
+	e := NewEmulator()
+	for _, c := range emulatorPages {
+		v, err := readPageFile(testFileName(c) + ".data")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = e.AddPage(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return e
+}
+
+func TestEmulatorPing(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	cgm.Cmd(Ping)
+	if cgm.Error() != nil {
+		t.Error(cgm.Error())
+	}
+}
+
+func TestEmulatorInvalidCommand(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	cgm.Cmd(Command(0xFE))
+	if cgm.Error() == nil {
+		t.Errorf("Cmd(FE) succeeded, want error")
+	}
+}
+
+func TestEmulatorPageRange(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	cases := []struct {
+		pageType    PageType
+		first, last int
+	}{
+		{ManufacturingData, 0, 0},
+		{SensorData, 469, 469},
+		{EGVData, 312, 312},
+		{UserEventData, -1, -1},
+	}
+	for _, c := range cases {
+		t.Run(c.pageType.String(), func(t *testing.T) {
+			first, last := cgm.ReadPageRange(c.pageType)
+			if cgm.Error() != nil {
+				t.Fatal(cgm.Error())
+			}
+			if first != c.first || last != c.last {
+				t.Errorf("ReadPageRange(%v) == (%d, %d), want (%d, %d)", c.pageType, first, last, c.first, c.last)
+			}
+		})
+	}
+}
+
+func TestEmulatorRecords(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	for _, c := range emulatorPages {
+		t.Run(c.pageType.String(), func(t *testing.T) {
+			records := cgm.ReadRecords(c.pageType, c.pageNumber)
+			if cgm.Error() != nil {
+				t.Fatal(cgm.Error())
+			}
+			checkRecords(t, records, testFileName(c)+".json")
+		})
+	}
+}
+
+func TestEmulatorHistory(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	all := cgm.ReadHistory(EGVData, time.Time{})
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(all) == 0 {
+		t.Fatal("ReadHistory returned no records")
+	}
+	since := all[len(all)/2].Time()
+	recent := cgm.ReadHistory(EGVData, since)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	for _, r := range recent {
+		if !r.Time().After(since) {
+			t.Errorf("ReadHistory(%v) returned record at %v", since, r.Time())
+		}
+	}
+	if len(recent) != len(all)/2 {
+		t.Errorf("ReadHistory(%v) returned %d records, want %d", since, len(recent), len(all)/2)
+	}
+	latest := cgm.ReadCount(EGVData, 3)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(latest) != 3 || !latest[0].Time().Equal(all[0].Time()) {
+		t.Errorf("ReadCount(3) == %v, want first 3 of %v", latest, all)
+	}
+}
+
+func TestEmulatorDisplayTime(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	want := parseTime("2017-09-17 11:13:17")
+	cgm.SetDisplayTime(want)
+	got := cgm.ReadDisplayTime()
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if !got.Equal(want) {
+		t.Errorf("ReadDisplayTime() == %v, want %v", got, want)
+	}
+}
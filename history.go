@@ -1,16 +1,39 @@
 package dexcom
 
 import (
+	"context"
 	"log"
+	"sort"
 	"time"
 )
 
 // ReadHistory returns records since the specified time.
 func (cgm *CGM) ReadHistory(pageType PageType, since time.Time) Records {
-	first, last := cgm.ReadPageRange(pageType)
+	return cgm.ReadHistoryContext(context.Background(), pageType, since)
+}
+
+// ReadHistoryContext is like ReadHistory, but it stops
+// when ctx is canceled or its deadline expires.
+func (cgm *CGM) ReadHistoryContext(ctx context.Context, pageType PageType, since time.Time) Records {
 	if cgm.Error() != nil {
 		return nil
 	}
+	results, err := readHistory(ctx, cgm, pageType, since)
+	cgm.SetError(err)
+	return results
+}
+
+// ReadHistoryE is like ReadHistory, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadHistoryE(pageType PageType, since time.Time) (Records, error) {
+	return readHistory(context.Background(), cgm, pageType, since)
+}
+
+func readHistory(ctx context.Context, src pageReader, pageType PageType, since time.Time) (Records, error) {
+	first, last, err := src.readPageRange(ctx, pageType)
+	if err != nil || first < 0 {
+		return nil, err
+	}
 	var results Records
 	proc := func(r Record) error {
 		t := r.Time()
@@ -21,16 +44,31 @@ func (cgm *CGM) ReadHistory(pageType PageType, since time.Time) Records {
 		results = append(results, r)
 		return nil
 	}
-	cgm.IterRecords(pageType, first, last, proc)
-	return results
+	err = iterRecords(ctx, src, pageType, first, last, proc)
+	return results, err
 }
 
 // ReadCount returns a specified number of most recent records.
 func (cgm *CGM) ReadCount(pageType PageType, count int) Records {
-	first, last := cgm.ReadPageRange(pageType)
 	if cgm.Error() != nil {
 		return nil
 	}
+	results, err := cgm.ReadCountE(pageType, count)
+	cgm.SetError(err)
+	return results
+}
+
+// ReadCountE is like ReadCount, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadCountE(pageType PageType, count int) (Records, error) {
+	return readCount(context.Background(), cgm, pageType, count)
+}
+
+func readCount(ctx context.Context, src pageReader, pageType PageType, count int) (Records, error) {
+	first, last, err := src.readPageRange(ctx, pageType)
+	if err != nil || first < 0 {
+		return nil, err
+	}
 	results := make(Records, 0, count)
 	proc := func(r Record) error {
 		results = append(results, r)
@@ -39,10 +77,97 @@ func (cgm *CGM) ReadCount(pageType PageType, count int) Records {
 		}
 		return nil
 	}
-	cgm.IterRecords(pageType, first, last, proc)
+	err = iterRecords(ctx, src, pageType, first, last, proc)
+	return results, err
+}
+
+// ReadRange returns records with times from "from" to "to", inclusive.
+// Instead of scanning backward from the last page, it uses a binary search
+// to find the last page that overlaps the range.
+// Display times can move backward (for example, when the clock is changed
+// for daylight saving time), so the search uses system times instead:
+// from and to are converted using the display time offset of the newest record.
+func (cgm *CGM) ReadRange(pageType PageType, from, to time.Time) Records {
+	if cgm.Error() != nil {
+		return nil
+	}
+	results, err := cgm.ReadRangeE(pageType, from, to)
+	cgm.SetError(err)
 	return results
 }
 
+// ReadRangeE is like ReadRange, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadRangeE(pageType PageType, from, to time.Time) (Records, error) {
+	return readRange(context.Background(), cgm, pageType, from, to)
+}
+
+func readRange(ctx context.Context, src pageReader, pageType PageType, from, to time.Time) (Records, error) {
+	first, last, err := src.readPageRange(ctx, pageType)
+	if err != nil || first < 0 {
+		return nil, err
+	}
+	offset, err := displayOffset(ctx, src, pageType, first, last)
+	if err != nil {
+		return nil, err
+	}
+	from, to = from.Add(-offset), to.Add(-offset)
+	last, err = searchPages(ctx, src, pageType, first, last, to)
+	if err != nil || last < first {
+		return nil, err
+	}
+	var results Records
+	proc := func(r Record) error {
+		t := r.Timestamp.SystemTime
+		if t.After(to) {
+			return nil
+		}
+		if t.Before(from) {
+			return IterationDone
+		}
+		results = append(results, r)
+		return nil
+	}
+	err = iterRecords(ctx, src, pageType, first, last, proc)
+	return results, err
+}
+
+// displayOffset returns the difference between the display time
+// and the system time of the newest record in the given pages.
+func displayOffset(ctx context.Context, src pageReader, pageType PageType, first, last int) (time.Duration, error) {
+	var offset time.Duration
+	err := iterRecords(ctx, src, pageType, first, last, func(r Record) error {
+		offset = r.Timestamp.DisplayTime.Sub(r.Timestamp.SystemTime)
+		return IterationDone
+	})
+	return offset, err
+}
+
+// searchPages returns the last page whose oldest record
+// has a system time that is not after t, or first-1 if there is none.
+func searchPages(ctx context.Context, src pageReader, pageType PageType, first, last int, t time.Time) (int, error) {
+	info, skipped := src.partitionInfo(ctx), src.lenient()
+	var err error
+	i := sort.Search(last-first+1, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		n := first + i
+		var v []byte
+		v, err = src.readPage(ctx, pageType, n)
+		if err != nil {
+			return true
+		}
+		var records Records
+		records, err = pageRecords(info, skipped, pageType, n, v)
+		if err != nil || len(records) == 0 {
+			return false
+		}
+		return records[len(records)-1].Timestamp.SystemTime.After(t)
+	})
+	return first + i - 1, err
+}
+
 // MergeHistory merges slices of records that are already
 // in reverse chronological order into a single ordered slice.
 func MergeHistory(slices ...Records) Records {
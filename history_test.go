@@ -0,0 +1,104 @@
+package dexcom
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadRange(t *testing.T) {
+	const first, count = 100, 40
+	t0 := parseTime("2017-09-17 11:00:00")
+	recordTime := func(k int) time.Time {
+		return t0.Add(time.Duration(k) * 5 * time.Minute)
+	}
+	e := NewEmulator()
+	for n := first; n < first+count; n++ {
+		var records Records
+		for i := 1; i >= 0; i-- {
+			k := 2*(n-first) + i
+			records = append(records, testRecord(recordTime(k), &EGVInfo{Glucose: uint16(100 + k), Trend: Flat}))
+		}
+		err := e.AddPage(makePage(EGVData, n, records...))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		from, to int // record indexes
+		min, max int // expected range of glucose values
+	}{
+		{10, 15, 110, 115},
+		{11, 11, 111, 111},
+		{-10, 3, 100, 103},
+		{75, 90, 175, 179},
+		{-10, 100, 100, 179},
+		{-10, -1, 0, -1},
+		{80, 90, 0, -1},
+	}
+	for _, c := range cases {
+		conn := &countingConn{Emulator: e}
+		cgm := &CGM{Connection: conn}
+		records := cgm.ReadRange(EGVData, recordTime(c.from), recordTime(c.to))
+		if cgm.Error() != nil {
+			t.Fatal(cgm.Error())
+		}
+		want := c.max - c.min + 1
+		if len(records) != want {
+			t.Errorf("ReadRange(%d, %d) returned %d records, want %d", c.from, c.to, len(records), want)
+			continue
+		}
+		for i, r := range records {
+			if r.Glucose() != uint16(c.max-i) {
+				t.Errorf("ReadRange(%d, %d) record %d has glucose %d, want %d", c.from, c.to, i, r.Glucose(), c.max-i)
+			}
+		}
+		// The binary search should read only a few pages,
+		// unless the range covers most of them.
+		if want <= 2*maxPagesPerRead && conn.pageReads > 10 {
+			t.Errorf("ReadRange(%d, %d) used %d page requests", c.from, c.to, conn.pageReads)
+		}
+	}
+}
+
+func TestReadRangeDisplayTimeChange(t *testing.T) {
+	const first, count = 100, 20
+	t0 := parseTime("2017-09-17 00:00:00")
+	// The display time is set back an hour after the first half of the records.
+	offset := func(k int) time.Duration {
+		if k < count {
+			return time.Hour
+		}
+		return 0
+	}
+	e := NewEmulator()
+	for n := first; n < first+count; n++ {
+		var records Records
+		for i := 1; i >= 0; i-- {
+			k := 2*(n-first) + i
+			sys := t0.Add(time.Duration(k) * 5 * time.Minute)
+			r := Record{Timestamp: Timestamp{SystemTime: sys, DisplayTime: sys.Add(offset(k))}, Info: &EGVInfo{Glucose: uint16(100 + k), Trend: Flat}}
+			records = append(records, r)
+		}
+		err := e.AddPage(makePage(EGVData, n, records...))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cgm := &CGM{Connection: e}
+	// Select the last record before the change and the first two after it,
+	// using display times at the current offset.
+	from := t0.Add(time.Duration(count-1) * 5 * time.Minute)
+	to := t0.Add(time.Duration(count+1) * 5 * time.Minute)
+	records := cgm.ReadRange(EGVData, from, to)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	var glucose []uint16
+	for _, r := range records {
+		glucose = append(glucose, r.Glucose())
+	}
+	want := []uint16{100 + count + 1, 100 + count, 100 + count - 1}
+	if len(glucose) != len(want) || glucose[0] != want[0] || glucose[1] != want[1] || glucose[2] != want[2] {
+		t.Errorf("ReadRange(%v, %v) returned glucose values %v, want %v", from, to, glucose, want)
+	}
+}
@@ -6,4 +6,19 @@ var (
 
 	// Ensure that *usbConn implements the Connection interface.
 	_ Connection = (*usbConn)(nil)
+
+	// Ensure that *Emulator implements the Connection interface.
+	_ Connection = (*Emulator)(nil)
+
+	// Ensure that *Recorder implements the Connection interface.
+	_ Connection = (*Recorder)(nil)
+
+	// Ensure that *Replayer implements the Connection interface.
+	_ Connection = (*Replayer)(nil)
+
+	// Ensure that *CGM implements the Source interface.
+	_ Source = (*CGM)(nil)
+
+	// Ensure that *PageStore implements the Source interface.
+	_ Source = (*PageStore)(nil)
 )
@@ -23,6 +23,14 @@ func marshalInt32(n int32) []byte {
 	return marshalUint32(uint32(n))
 }
 
+func marshalUint64(n uint64) []byte {
+	return append(marshalUint32(uint32(n&0xFFFFFFFF)), marshalUint32(uint32(n>>32))...)
+}
+
+func marshalFloat64(f float64) []byte {
+	return marshalUint64(math.Float64bits(f))
+}
+
 func unmarshalUint16(v []byte) uint16 {
 	return uint16(v[0]) | uint16(v[1])<<8
 }
@@ -1,7 +1,6 @@
 package dexcom
 
 import (
-	"fmt"
 	"log"
 	"time"
 
@@ -10,52 +9,111 @@ import (
 
 // NightscoutEntries converts records (in reverse-chronological order)
 // into a Nightscout entries.  Neighboring Sensor and EGV records are merged.
+// Records of other types are skipped; use NightscoutTreatments for user events.
 func NightscoutEntries(records Records) nightscout.Entries {
-	entries := make(nightscout.Entries, len(records))
-	for i, r := range records {
-		entries[i] = r.nightscoutEntry()
+	entries := make(nightscout.Entries, 0, len(records))
+	for _, r := range records {
+		e, ok := r.nightscoutEntry()
+		if !ok {
+			continue
+		}
+		entries = append(entries, e)
 	}
 	return mergeGlucoseEntries(entries)
 }
 
-func (r Record) nightscoutEntry() nightscout.Entry {
+// NightscoutTreatment represents a Nightscout treatment.
+type NightscoutTreatment struct {
+	CreatedAt time.Time `json:"created_at"`
+	EventType string    `json:"eventType"`
+	EnteredBy string    `json:"enteredBy,omitempty"`
+	Carbs     *int      `json:"carbs,omitempty"`
+	Insulin   *float64  `json:"insulin,omitempty"`
+	Duration  *int      `json:"duration,omitempty"` // minutes
+	Notes     string    `json:"notes,omitempty"`
+}
+
+// NightscoutTreatments converts the user event records among records
+// into Nightscout treatments.  Other records are ignored.
+// User events with no corresponding treatment are returned in skipped.
+func NightscoutTreatments(records Records) (treatments []NightscoutTreatment, skipped Records) {
+	for _, r := range records {
+		e, isUserEvent := r.Info.(*UserEventInfo)
+		if !isUserEvent {
+			continue
+		}
+		t, ok := e.nightscoutTreatment()
+		if !ok {
+			skipped = append(skipped, r)
+			continue
+		}
+		treatments = append(treatments, t)
+	}
+	return treatments, skipped
+}
+
+func (e UserEventInfo) nightscoutTreatment() (NightscoutTreatment, bool) {
+	t := NightscoutTreatment{
+		CreatedAt: e.EventTime,
+		EnteredBy: nightscout.Device(),
+	}
+	switch e.Type {
+	case CarbsEvent:
+		carbs := e.Carbs()
+		t.EventType = "Carb Correction"
+		t.Carbs = &carbs
+	case InsulinEvent:
+		insulin := e.Insulin()
+		t.EventType = "Correction Bolus"
+		t.Insulin = &insulin
+	case HealthEvent:
+		t.EventType = "Note"
+		t.Notes = e.Health().String()
+	case ExerciseEvent:
+		minutes := int(e.Duration() / time.Minute)
+		t.EventType = "Exercise"
+		t.Duration = &minutes
+		t.Notes = e.Exercise().String()
+	default:
+		return t, false
+	}
+	return t, true
+}
+
+// nightscoutEntry converts a sensor, EGV, meter, or calibration record
+// into a Nightscout entry.  It returns false for other records.
+func (r Record) nightscoutEntry() (nightscout.Entry, bool) {
 	t := r.Time()
 	e := nightscout.Entry{
 		Date:       nightscout.Date(t),
 		DateString: t.Format(nightscout.DateStringLayout),
 		Device:     nightscout.Device(),
 	}
-	if r.Sensor != nil {
-		info := r.Sensor
+	switch info := r.Info.(type) {
+	case *SensorInfo:
 		e.Type = nightscout.SGVType
 		e.Unfiltered = int(info.Unfiltered)
 		e.Filtered = int(info.Filtered)
 		e.RSSI = int(info.RSSI)
-		return e
-	}
-	if r.EGV != nil {
-		info := r.EGV
+		return e, true
+	case *EGVInfo:
 		e.Type = nightscout.SGVType
 		e.SGV = int(info.Glucose)
 		e.Direction = nightscoutTrend(info.Trend)
 		e.Noise = int(info.Noise)
-		return e
-	}
-	if r.Meter != nil {
-		info := r.Meter
+		return e, true
+	case *MeterInfo:
 		e.Type = nightscout.MBGType
 		e.MBG = int(info.Glucose)
-		return e
-	}
-	if r.Calibration != nil {
-		info := r.Calibration
+		return e, true
+	case *CalibrationInfo:
 		e.Type = nightscout.CalType
 		e.Slope = info.Slope
 		e.Intercept = info.Intercept
 		e.Scale = info.Scale
-		return e
+		return e, true
 	}
-	panic(fmt.Sprintf("nightscoutEntry %+v", r))
+	return e, false
 }
 
 func nightscoutTrend(t Trend) string {
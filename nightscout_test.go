@@ -46,7 +46,7 @@ func nsDate(s string) int64 {
 var (
 	r1 = Record{
 		Timestamp: ts("2017-09-17T01:13:51-04:00"),
-		Calibration: &CalibrationInfo{
+		Info: &CalibrationInfo{
 			Slope:     939.6817717490421,
 			Intercept: 35926.604186515906,
 			Scale:     1,
@@ -54,13 +54,13 @@ var (
 	}
 	r2 = Record{
 		Timestamp: ts("2017-09-17T01:13:49-04:00"),
-		Meter: &MeterInfo{
+		Info: &MeterInfo{
 			Glucose: 128,
 		},
 	}
 	r3 = Record{
 		Timestamp: ts("2017-09-17T11:13:17-04:00"),
-		EGV: &EGVInfo{
+		Info: &EGVInfo{
 			Glucose: 84,
 			Trend:   Flat,
 			Noise:   1,
@@ -68,7 +68,7 @@ var (
 	}
 	r4 = Record{
 		Timestamp: ts("2017-09-17T11:13:16-04:00"),
-		Sensor: &SensorInfo{
+		Info: &SensorInfo{
 			Unfiltered: 119088,
 			Filtered:   110288,
 			RSSI:       -62,
@@ -137,8 +137,9 @@ func TestNightscoutEntry(t *testing.T) {
 	}
 	for _, c := range cases {
 		t.Run("", func(t *testing.T) {
-			e := Entry(c.r.nightscoutEntry())
-			if e != c.e {
+			v, ok := c.r.nightscoutEntry()
+			e := Entry(v)
+			if !ok || e != c.e {
 				t.Errorf("nightscoutEntry(%v) == %v, want %v", c.r, e, c.e)
 			}
 		})
@@ -154,6 +155,10 @@ func TestNightscoutEntries(t *testing.T) {
 			Records{r3, r4},
 			Entries{e5},
 		},
+		{
+			Records{r3, {Timestamp: r4.Timestamp, Info: &InsertionInfo{Event: Started}}},
+			Entries{e3},
+		},
 	}
 	for _, c := range cases {
 		t.Run("", func(t *testing.T) {
@@ -2,15 +2,27 @@ package dexcom
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 )
 
 const (
 	startOfMessage = 1
 	minPacket      = 6
 	maxPacket      = 1590
+
+	// Maximum time to wait for each byte of a response.
+	receiveTimeout = 5 * time.Second
+
+	// Time without incoming data after which draining ends.
+	drainTimeout = 100 * time.Millisecond
 )
 
+var errReceiveTimeout = errors.New("receive timeout")
+
 func marshalPacket(cmd Command, data []byte) []byte {
 	buf := bytes.Buffer{}
 	buf.WriteByte(startOfMessage)
@@ -23,65 +35,350 @@ func marshalPacket(cmd Command, data []byte) []byte {
 	return buf.Bytes()
 }
 
-func (cgm *CGM) sendPacket(pkt []byte) {
-	err := cgm.Send(pkt)
-	cgm.SetError(err)
+// ResponseError indicates that the receiver responded to a command
+// with a code other than Ack.
+type ResponseError struct {
+	Command Command
+	Code    Command
+	Header  []byte
+}
+
+func (e ResponseError) Error() string {
+	return fmt.Sprintf("%v: unexpected response code %v in header % X", e.Command, e.Code, e.Header)
+}
+
+// IsResponseCode reports whether err is a ResponseError with the given code.
+func IsResponseCode(err error, code Command) bool {
+	var e ResponseError
+	return errors.As(err, &e) && e.Code == code
+}
+
+// framingError indicates that a response packet could not be delimited.
+type framingError struct {
+	error
 }
 
-func (cgm *CGM) receivePacket() []byte {
+// receivePacket reads a complete response packet, even if its
+// response code indicates an error, to stay synchronized with the device.
+func (cgm *CGM) receivePacket(cmd Command) ([]byte, error) {
 	header := make([]byte, 4)
 	err := cgm.Receive(header)
 	if err != nil {
-		cgm.SetError(err)
-		return nil
+		return nil, err
 	}
 	if header[0] != startOfMessage {
-		cgm.SetError(fmt.Errorf("unexpected message header % X", header))
-		return nil
-	}
-	rc := Command(header[3])
-	if rc != Ack {
-		cgm.SetError(fmt.Errorf("unexpected response code %v in header % X", rc, header))
-		return nil
+		return nil, framingError{fmt.Errorf("unexpected message header % X", header)}
 	}
 	length := unmarshalUint16(header[1:3])
 	if length < minPacket || length > maxPacket {
-		cgm.SetError(fmt.Errorf("invalid packet length %d in header % X", length, header))
-		return nil
+		return nil, framingError{fmt.Errorf("invalid packet length %d in header % X", length, header)}
 	}
 	n := length - minPacket
 	data := make([]byte, n+2)
 	err = cgm.Receive(data)
 	if err != nil {
-		cgm.SetError(err)
-		return nil
+		return nil, err
 	}
 	crc := unmarshalUint16(data[n:])
 	data = data[:n]
 	body := append(header, data...)
 	calc := crc16(body)
 	if crc != calc {
-		cgm.SetError(CRCError{
+		return data, CRCError{
 			Kind:     "packet",
 			Received: crc,
 			Computed: calc,
 			PageType: InvalidPage,
 			Data:     body,
-		})
+		}
+	}
+	rc := Command(header[3])
+	if rc != Ack {
+		return nil, ResponseError{Command: cmd, Code: rc, Header: header}
 	}
-	return data
+	return data, nil
 }
 
-// Cmd creates a Dexcom packet with the given command and parameters,
-// sends it to the device, and returns the response.
-func (cgm *CGM) Cmd(cmd Command, params ...byte) []byte {
-	if cgm.Error() != nil {
+// exchange sends a packet to the device and returns the response.
+func (cgm *CGM) exchange(pkt []byte) ([]byte, error) {
+	err := cgm.Send(pkt)
+	if err != nil {
+		return nil, err
+	}
+	return cgm.receivePacket(Command(pkt[3]))
+}
+
+// RetryPolicy specifies how many times a command is attempted
+// when the exchange fails because of a transmission error,
+// and how long to wait before each retry.
+// Attempts less than 1 are treated as 1.
+// Commands that change the receiver's state are retried only
+// if the receiver responds with Nak or IncompletePacketReceived,
+// since otherwise they may already have been executed.
+type RetryPolicy struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+// SetRetryPolicy sets the retry policy used by Cmd
+// and the methods that are built on it.
+func (cgm *CGM) SetRetryPolicy(p RetryPolicy) {
+	cgm.retryMu.Lock()
+	cgm.retry = p
+	cgm.retryMu.Unlock()
+}
+
+func (cgm *CGM) retryPolicy() RetryPolicy {
+	cgm.retryMu.Lock()
+	defer cgm.retryMu.Unlock()
+	return cgm.retry
+}
+
+// lock waits for any other exchange to end,
+// giving up if ctx is done first.
+func (cgm *CGM) lock(ctx context.Context) error {
+	cgm.semOnce.Do(func() {
+		cgm.sem = make(chan struct{}, 1)
+	})
+	select {
+	case cgm.sem <- struct{}{}:
 		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (cgm *CGM) unlock() {
+	<-cgm.sem
+}
+
+// IsTransient reports whether err indicates a transmission error
+// (a NAK, incomplete packet, receiver error, malformed or corrupted
+// response, or receive timeout) that may not recur if the command is retried.
+// Other errors, such as InvalidCommand, InvalidParam, or InvalidMode
+// responses, will persist until the command or the receiver's mode is changed.
+func IsTransient(err error) bool {
+	var crcErr CRCError
+	var frameErr framingError
+	var respErr ResponseError
+	switch {
+	case errors.As(err, &crcErr), errors.As(err, &frameErr):
+		return true
+	case errors.As(err, &respErr):
+		switch respErr.Code {
+		case Nak, IncompletePacketReceived, ReceiverError:
+			return true
+		}
+		return false
+	}
+	return errors.Is(err, errReceiveTimeout)
+}
+
+// retryable reports whether cmd may be sent again after err,
+// as described for RetryPolicy.
+func retryable(cmd Command, err error) bool {
+	if !IsTransient(err) {
+		return false
+	}
+	if cmd.readOnly() {
+		return true
+	}
+	return IsResponseCode(err, Nak) || IsResponseCode(err, IncompletePacketReceived)
+}
+
+// readOnly reports whether cmd only reads from the receiver.
+func (cmd Command) readOnly() bool {
+	switch cmd {
+	case Ping,
+		ReadFirmwareHeader,
+		ReadDatabasePartitionInfo,
+		ReadDatabasePageRange,
+		ReadDatabasePages,
+		ReadDatabasePageHeader,
+		ReadTransmitterID,
+		ReadLanguage,
+		ReadDisplayTimeOffset,
+		ReadRTC,
+		ReadBatteryLevel,
+		ReadSystemTime,
+		ReadSystemTimeOffset,
+		ReadGlucoseUnits,
+		ReadBlindMode,
+		ReadClockMode,
+		ReadDeviceMode,
+		ReadBatteryState,
+		ReadHardwareID,
+		ReadFirmwareSettings,
+		ReadEnableSetupWizardFlag,
+		ReadSetupWizardState,
+		ReadChargerCurrentSetting:
+		return true
+	}
+	return false
+}
+
+// A drainer can discard any data waiting to be received.
+type drainer interface {
+	Drain()
+}
+
+// A deadliner can set the time after which receiving data fails,
+// which interrupts a Receive in progress.
+// A zero time means no deadline.
+type deadliner interface {
+	SetDeadline(time.Time) error
+}
+
+// A time in the past, used to interrupt a Receive.
+var expired = time.Unix(1, 0)
+
+// drain discards any unread response data, if the connection allows it,
+// so that the next response will be read from its beginning.
+func (cgm *CGM) drain() {
+	d, ok := cgm.Connection.(drainer)
+	if ok {
+		d.Drain()
 	}
+}
+
+// cmd performs a command exchange, retrying it according to the
+// retry policy if a transient error occurs.
+// Exchanges are serialized, so that concurrent commands
+// do not interleave their packets on the connection.
+func (cgm *CGM) cmd(ctx context.Context, cmd Command, params []byte) ([]byte, error) {
 	pkt := marshalPacket(cmd, params)
-	cgm.sendPacket(pkt)
+	retry := cgm.retryPolicy()
+	err := cgm.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		r, pending := cgm.exchangeContext(ctx, pkt)
+		if pending != nil {
+			// Keep other commands waiting until the abandoned exchange ends.
+			go func() {
+				if r := <-pending; r.err != nil {
+					cgm.drain()
+				}
+				cgm.unlock()
+			}()
+			return r.data, r.err
+		}
+		if r.err == nil || attempt >= retry.Attempts || !retryable(cmd, r.err) {
+			cgm.unlock()
+			return r.data, r.err
+		}
+		msg := fmt.Sprintf("%v: %v", cmd, r.err)
+		if errors.As(r.err, new(ResponseError)) {
+			// The error already includes the command.
+			msg = r.err.Error()
+		}
+		log.Printf("%s (attempt %d of %d)", msg, attempt, retry.Attempts)
+		cgm.drain()
+		if retry.Delay != 0 {
+			select {
+			case <-time.After(retry.Delay):
+			case <-ctx.Done():
+				cgm.unlock()
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+type exchangeResult struct {
+	data []byte
+	err  error
+}
+
+// exchangeContext performs an exchange, giving up if ctx is done first.
+// If the connection supports deadlines, the exchange is interrupted
+// and any partial response is drained, so the connection can be used
+// for the next exchange. Otherwise the exchange is abandoned,
+// and a channel is returned that will receive the result
+// when the exchange finally ends.
+func (cgm *CGM) exchangeContext(ctx context.Context, pkt []byte) (exchangeResult, <-chan exchangeResult) {
+	err := ctx.Err()
+	if err != nil {
+		return exchangeResult{err: err}, nil
+	}
+	if ctx.Done() == nil {
+		v, err := cgm.exchange(pkt)
+		return exchangeResult{data: v, err: err}, nil
+	}
+	if d, ok := cgm.Connection.(deadliner); ok {
+		deadline, _ := ctx.Deadline()
+		if d.SetDeadline(deadline) == nil {
+			return cgm.exchangeDeadline(ctx, d, pkt), nil
+		}
+	}
+	done := make(chan exchangeResult, 1)
+	go func() {
+		v, err := cgm.exchange(pkt)
+		done <- exchangeResult{data: v, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r, nil
+	case <-ctx.Done():
+		return exchangeResult{err: ctx.Err()}, done
+	}
+}
+
+// exchangeDeadline performs an exchange on a connection whose deadline
+// has been set from ctx, interrupting it if ctx is canceled.
+func (cgm *CGM) exchangeDeadline(ctx context.Context, d deadliner, pkt []byte) exchangeResult {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			_ = d.SetDeadline(expired)
+		case <-stop:
+		}
+	}()
+	v, err := cgm.exchange(pkt)
+	close(stop)
+	<-stopped
+	_ = d.SetDeadline(time.Time{})
+	if err != nil {
+		if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			// The connection's deadline may pass just before ctx is done.
+			<-ctx.Done()
+		}
+	}
+	if err != nil && ctx.Err() != nil {
+		cgm.drain()
+		return exchangeResult{err: ctx.Err()}
+	}
+	return exchangeResult{data: v, err: err}
+}
+
+// Cmd creates a Dexcom packet with the given command and parameters,
+// sends it to the device, and returns the response.
+func (cgm *CGM) Cmd(cmd Command, params ...byte) []byte {
+	return cgm.CmdContext(context.Background(), cmd, params...)
+}
+
+// CmdContext is like Cmd, but it gives up waiting for the response
+// when ctx is canceled or its deadline expires,
+// and the error is set to ctx.Err().
+// If the connection supports deadlines, as USB and BLE connections do,
+// any partial response is discarded so that later commands can proceed.
+// Otherwise later commands wait until the abandoned exchange ends.
+func (cgm *CGM) CmdContext(ctx context.Context, cmd Command, params ...byte) []byte {
 	if cgm.Error() != nil {
 		return nil
 	}
-	return cgm.receivePacket()
+	v, err := cgm.cmd(ctx, cmd, params)
+	cgm.SetError(err)
+	return v
+}
+
+// CmdE is like Cmd, but it returns any error to the caller
+// instead of checking or setting the CGM's error state,
+// so that concurrent callers do not see each other's errors.
+func (cgm *CGM) CmdE(cmd Command, params ...byte) ([]byte, error) {
+	return cgm.cmd(context.Background(), cmd, params)
 }
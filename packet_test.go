@@ -29,3 +29,36 @@ func TestPacket(t *testing.T) {
 		})
 	}
 }
+
+func TestResponseError(t *testing.T) {
+	cases := []struct {
+		cmd       Command
+		params    []byte
+		code      Command
+		transient bool
+	}{
+		{Command(0xFE), nil, InvalidCommand, false},
+		{ReadDatabasePageRange, nil, InvalidParam, false},
+		{ReadDatabasePages, []byte{byte(EGVData), 0, 0, 0, 0, 1}, InvalidParam, false},
+	}
+	for _, c := range cases {
+		t.Run(c.cmd.String(), func(t *testing.T) {
+			cgm := &CGM{Connection: newTestEmulator(t)}
+			cgm.Cmd(c.cmd, c.params...)
+			err := cgm.Error()
+			e, ok := err.(ResponseError)
+			if !ok {
+				t.Fatalf("Cmd(%v, % X) returned %v, want ResponseError", c.cmd, c.params, err)
+			}
+			if e.Command != c.cmd || e.Code != c.code {
+				t.Errorf("Cmd(%v, % X) returned %v response to %v, want %v response to %v", c.cmd, c.params, e.Code, e.Command, c.code, c.cmd)
+			}
+			if !IsResponseCode(err, c.code) {
+				t.Errorf("IsResponseCode(%v, %v) == false, want true", err, c.code)
+			}
+			if IsTransient(err) != c.transient {
+				t.Errorf("IsTransient(%v) == %v, want %v", err, !c.transient, c.transient)
+			}
+		})
+	}
+}
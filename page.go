@@ -2,6 +2,7 @@ package dexcom
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 )
@@ -44,16 +45,50 @@ var recordLength = map[PageType]int{
 	CalibrationData:   249, // except for non-Share receivers, see below
 	InsertionTimeData: 15,
 	MeterData:         16,
+	UserEventData:     20,
+}
+
+// Record revisions used by current receivers, if other than 1.
+var recordRevision = map[PageType]byte{
+	EGVData:         2,
+	CalibrationData: 3,
+}
+
+func pageRevision(pageType PageType) byte {
+	rev := recordRevision[pageType]
+	if rev == 0 {
+		rev = 1
+	}
+	return rev
 }
 
 // ReadPageRange returns the starting and ending page for a given PageType.
 // The page numbers can be -1 if there are no entries (for example, USER_EVENT_DATA).
 func (cgm *CGM) ReadPageRange(pageType PageType) (int, int) {
-	v := cgm.Cmd(ReadDatabasePageRange, byte(pageType))
 	if cgm.Error() != nil {
 		return -1, -1
 	}
-	return int(unmarshalInt32(v[:4])), int(unmarshalInt32(v[4:]))
+	first, last, err := cgm.ReadPageRangeE(pageType)
+	cgm.SetError(err)
+	return first, last
+}
+
+// ReadPageRangeE is like ReadPageRange, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadPageRangeE(pageType PageType) (int, int, error) {
+	return cgm.readPageRange(context.Background(), pageType)
+}
+
+func (cgm *CGM) readPageRange(ctx context.Context, pageType PageType) (int, int, error) {
+	v, err := cgm.cmd(ctx, ReadDatabasePageRange, []byte{byte(pageType)})
+	if err != nil {
+		return -1, -1, err
+	}
+	first, last := int(unmarshalInt32(v[:4])), int(unmarshalInt32(v[4:]))
+	if cache := cgm.pageCache(); cache != nil {
+		cache.setRange(pageType, first, last)
+	}
+	return first, last, nil
 }
 
 // CRCError indicates that a CRC error was detected.
@@ -65,6 +100,10 @@ type CRCError struct {
 	Data               []byte
 }
 
+// CRCErrorFunc represents a function that is called with the CRCError
+// for each record skipped in lenient mode.
+type CRCErrorFunc func(CRCError)
+
 func (e CRCError) Error() string {
 	if e.PageType == InvalidPage {
 		return fmt.Sprintf("bad %s CRC (received %02X, computed %02X); data = % X", e.Kind, e.Received, e.Computed, e.Data)
@@ -74,31 +113,203 @@ func (e CRCError) Error() string {
 
 // ReadPage reads the specified page.
 func (cgm *CGM) ReadPage(pageType PageType, pageNumber int) []byte {
+	return cgm.ReadPageContext(context.Background(), pageType, pageNumber)
+}
+
+// ReadPageContext is like ReadPage, but it gives up
+// when ctx is canceled or its deadline expires.
+func (cgm *CGM) ReadPageContext(ctx context.Context, pageType PageType, pageNumber int) []byte {
+	if cgm.Error() != nil {
+		return nil
+	}
+	v, err := cgm.readPage(ctx, pageType, pageNumber)
+	cgm.SetError(err)
+	return v
+}
+
+// ReadPageE is like ReadPage, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadPageE(pageType PageType, pageNumber int) ([]byte, error) {
+	return cgm.readPage(context.Background(), pageType, pageNumber)
+}
+
+func (cgm *CGM) readPage(ctx context.Context, pageType PageType, pageNumber int) ([]byte, error) {
+	cache := cgm.pageCache()
+	if cache != nil {
+		if v := cache.get(ctx, cgm, pageType, pageNumber); v != nil {
+			return v, nil
+		}
+	}
+	v, err := cgm.readPageBlock(ctx, pageType, pageNumber, 1)
+	if err == nil && cache != nil {
+		cache.put(ctx, cgm, pageType, pageNumber, v)
+	}
+	return v, err
+}
+
+// readPageBlock reads count consecutive pages in a single request.
+func (cgm *CGM) readPageBlock(ctx context.Context, pageType PageType, firstPage int, count int) ([]byte, error) {
 	buf := bytes.Buffer{}
 	buf.WriteByte(byte(pageType))
-	buf.Write(marshalInt32(int32(pageNumber)))
-	buf.WriteByte(1)
-	return cgm.Cmd(ReadDatabasePages, buf.Bytes()...)
+	buf.Write(marshalInt32(int32(firstPage)))
+	buf.WriteByte(byte(count))
+	return cgm.cmd(ctx, ReadDatabasePages, buf.Bytes())
+}
+
+const (
+	pageSize = headerSize + pageDataSize
+
+	// Maximum number of pages that fit in a single response packet.
+	maxPagesPerRead = (maxPacket - minPacket) / pageSize
+)
+
+// ReadPages reads count consecutive pages starting with firstPage,
+// requesting as many pages per packet as will fit.
+func (cgm *CGM) ReadPages(pageType PageType, firstPage int, count int) [][]byte {
+	if cgm.Error() != nil {
+		return nil
+	}
+	pages, err := cgm.ReadPagesE(pageType, firstPage, count)
+	cgm.SetError(err)
+	return pages
+}
+
+// ReadPagesE is like ReadPages, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadPagesE(pageType PageType, firstPage int, count int) ([][]byte, error) {
+	return cgm.readPages(context.Background(), pageType, firstPage, count)
+}
+
+func (cgm *CGM) readPages(ctx context.Context, pageType PageType, firstPage int, count int) ([][]byte, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("%v: invalid page count %d", pageType, count)
+	}
+	cache := cgm.pageCache()
+	cached := make([][]byte, count)
+	if cache != nil {
+		for i := range cached {
+			cached[i] = cache.get(ctx, cgm, pageType, firstPage+i)
+		}
+	}
+	pages := make([][]byte, 0, count)
+	for i := 0; i < count; {
+		if cached[i] != nil {
+			pages = append(pages, cached[i])
+			i++
+			continue
+		}
+		// Read the next run of uncached pages.
+		k := 1
+		for k < maxPagesPerRead && i+k < count && cached[i+k] == nil {
+			k++
+		}
+		n := firstPage + i
+		v, err := cgm.readPageBlock(ctx, pageType, n, k)
+		if err != nil {
+			return pages, err
+		}
+		if len(v) != k*pageSize {
+			return pages, fmt.Errorf("%v pages %d to %d: unexpected response length (%d)", pageType, n, n+k-1, len(v))
+		}
+		for j := 0; j < k; j++ {
+			page := v[j*pageSize : (j+1)*pageSize]
+			if cache != nil {
+				cache.put(ctx, cgm, pageType, n+j, page)
+			}
+			pages = append(pages, page)
+		}
+		i += k
+	}
+	return pages, nil
 }
 
 // PageInfo represents a page of raw records.
+// The Records field is nil if only the page header has been read.
 type PageInfo struct {
-	Type    PageType
-	Number  int
-	Records [][]byte
+	Type       PageType
+	Number     int
+	FirstIndex int // index of the first record in the page
+	NumRecords int
+	Revision   byte // record revision
+	Reserved   [3]int32
+	Records    [][]byte
+}
+
+// ReadPageHeader reads the header of the specified page.
+func (cgm *CGM) ReadPageHeader(pageType PageType, pageNumber int) *PageInfo {
+	if cgm.Error() != nil {
+		return nil
+	}
+	page, err := cgm.ReadPageHeaderE(pageType, pageNumber)
+	cgm.SetError(err)
+	return page
+}
+
+// ReadPageHeaderE is like ReadPageHeader, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadPageHeaderE(pageType PageType, pageNumber int) (*PageInfo, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte(byte(pageType))
+	buf.Write(marshalInt32(int32(pageNumber)))
+	v, err := cgm.CmdE(ReadDatabasePageHeader, buf.Bytes()...)
+	if err != nil {
+		return nil, err
+	}
+	page, err := UnmarshalPageHeader(v)
+	if err != nil {
+		return nil, err
+	}
+	if page.Type != pageType || page.Number != pageNumber {
+		return page, fmt.Errorf("%v page %d: unexpected header for %v page %d", pageType, pageNumber, page.Type, page.Number)
+	}
+	return page, nil
 }
 
 // ReadRawRecords reads the specified page and returns its records as raw byte slices.
 func (cgm *CGM) ReadRawRecords(pageType PageType, pageNumber int) [][]byte {
-	v := cgm.ReadPage(pageType, pageNumber)
 	if cgm.Error() != nil {
 		return nil
 	}
-	page, err := UnmarshalPage(v)
+	data, err := cgm.ReadRawRecordsE(pageType, pageNumber)
+	cgm.SetError(err)
+	return data
+}
+
+// ReadRawRecordsE is like ReadRawRecords, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadRawRecordsE(pageType PageType, pageNumber int) ([][]byte, error) {
+	return readRawRecords(context.Background(), cgm, pageType, pageNumber)
+}
+
+// pageReader provides the raw page access on which
+// the record-reading functions are built.
+type pageReader interface {
+	readPageRange(ctx context.Context, pageType PageType) (int, int, error)
+	readPage(ctx context.Context, pageType PageType, pageNumber int) ([]byte, error)
+	readPages(ctx context.Context, pageType PageType, firstPage int, count int) ([][]byte, error)
+	partitionInfo(ctx context.Context) *PartitionInfo
+	lenient() CRCErrorFunc
+}
+
+func readRawRecords(ctx context.Context, src pageReader, pageType PageType, pageNumber int) ([][]byte, error) {
+	v, err := src.readPage(ctx, pageType, pageNumber)
+	if err != nil {
+		return nil, err
+	}
+	return rawRecords(src.partitionInfo(ctx), src.lenient(), pageType, pageNumber, v)
+}
+
+// rawRecords slices the data of the specified page into raw records.
+// If skipped is non-nil, records with invalid CRCs are skipped
+// and skipped is called for each one.
+func rawRecords(info *PartitionInfo, skipped CRCErrorFunc, pageType PageType, pageNumber int, v []byte) ([][]byte, error) {
+	page, crcErrors, err := info.unmarshalPage(v, skipped != nil)
+	for _, e := range crcErrors {
+		skipped(e)
+	}
 	if err != nil {
 		if page == nil {
-			cgm.SetError(err)
-			return nil
+			return nil, err
 		}
 		err = fmt.Errorf("%v page %d: %v", page.Type, page.Number, err)
 	} else if page.Type != pageType {
@@ -106,25 +317,49 @@ func (cgm *CGM) ReadRawRecords(pageType PageType, pageNumber int) [][]byte {
 	} else if page.Number != pageNumber {
 		err = fmt.Errorf("%v page %d: unexpected page number (%d)", pageType, pageNumber, page.Number)
 	}
-	cgm.SetError(err)
-	return page.Records
+	return page.Records, err
 }
 
 // ReadRecords reads the specified page and returns its records.
 func (cgm *CGM) ReadRecords(pageType PageType, pageNumber int) Records {
-	data := cgm.ReadRawRecords(pageType, pageNumber)
 	if cgm.Error() != nil {
 		return nil
 	}
+	records, err := cgm.ReadRecordsE(pageType, pageNumber)
+	cgm.SetError(err)
+	return records
+}
+
+// ReadRecordsE is like ReadRecords, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadRecordsE(pageType PageType, pageNumber int) (Records, error) {
+	return readRecords(context.Background(), cgm, pageType, pageNumber)
+}
+
+func readRecords(ctx context.Context, src pageReader, pageType PageType, pageNumber int) (Records, error) {
+	v, err := src.readPage(ctx, pageType, pageNumber)
+	if err != nil {
+		return nil, err
+	}
+	return pageRecords(src.partitionInfo(ctx), src.lenient(), pageType, pageNumber, v)
+}
+
+// pageRecords unmarshals the records in the data of the specified page.
+func pageRecords(info *PartitionInfo, skipped CRCErrorFunc, pageType PageType, pageNumber int, v []byte) (Records, error) {
+	data, err := rawRecords(info, skipped, pageType, pageNumber, v)
+	if err != nil {
+		return nil, err
+	}
 	records, err := UnmarshalRecords(pageType, data)
 	if err != nil {
-		cgm.SetError(fmt.Errorf("%v page %d: %v", pageType, pageNumber, err))
+		err = fmt.Errorf("%v page %d: %v", pageType, pageNumber, err)
 	}
-	return records
+	return records, err
 }
 
 const (
 	headerSize       = 28
+	pageDataSize     = 500
 	oldCalRecordRev  = 2
 	oldCalRecordSize = 148
 )
@@ -132,40 +367,58 @@ const (
 // UnmarshalPage validates the CRC of the given page data and
 // uses the page type to slice the data into raw records.
 func UnmarshalPage(v []byte) (*PageInfo, error) {
-	if len(v) < headerSize {
-		return nil, fmt.Errorf("invalid page length (%d)", len(v))
+	return (*PartitionInfo)(nil).UnmarshalPage(v)
+}
+
+// UnmarshalPage is like the UnmarshalPage function,
+// but it uses the record lengths in the partition info when they apply
+// to the page's record revision.
+// A nil PartitionInfo uses only the built-in record lengths.
+func (info *PartitionInfo) UnmarshalPage(v []byte) (*PageInfo, error) {
+	page, _, err := info.unmarshalPage(v, false)
+	return page, err
+}
+
+// UnmarshalPageLenient is like UnmarshalPage, but instead of stopping
+// at the first record with an invalid CRC, it returns every record
+// with a valid CRC, together with a CRCError for each invalid one.
+func UnmarshalPageLenient(v []byte) (*PageInfo, []CRCError, error) {
+	return (*PartitionInfo)(nil).UnmarshalPageLenient(v)
+}
+
+// UnmarshalPageLenient is like the UnmarshalPageLenient function,
+// but it uses the record lengths in the partition info when they apply.
+func (info *PartitionInfo) UnmarshalPageLenient(v []byte) (*PageInfo, []CRCError, error) {
+	return info.unmarshalPage(v, true)
+}
+
+func (info *PartitionInfo) unmarshalPage(v []byte, lenient bool) (*PageInfo, []CRCError, error) {
+	page, err := UnmarshalPageHeader(v)
+	if err != nil {
+		return nil, nil, err
 	}
-	h := v[:headerSize]
 	v = v[headerSize:]
-	crc := unmarshalUint16(h[headerSize-2:])
-	calc := crc16(h[:headerSize-2])
-	if crc != calc {
-		return nil, CRCError{
-			Kind:     "page",
-			Received: crc,
-			Computed: calc,
-			Data:     h,
+	numRecords := page.NumRecords
+	pageType := page.Type
+	rev := page.Revision
+	pageNumber := page.Number
+	recordLen, known := info.recordLength(pageType, rev)
+	if !known {
+		recordLen = recordLength[pageType]
+		if pageType == CalibrationData && rev <= oldCalRecordRev {
+			recordLen = oldCalRecordSize
 		}
 	}
-	// firstIndex := int(unmarshalInt32(h[0:4]))
-	numRecords := int(unmarshalInt32(h[4:8]))
-	pageType := PageType(h[8])
-	rev := h[9]
-	pageNumber := int(unmarshalInt32(h[10:14]))
-	// r1 := unmarshalInt32(h[14:18])
-	// r2 := unmarshalInt32(h[18:22])
-	// r3 := unmarshalInt32(h[22:26])
-	page := PageInfo{Type: pageType, Number: pageNumber}
-	recordLen := recordLength[pageType]
-	if pageType == CalibrationData && rev <= oldCalRecordRev {
-		recordLen = oldCalRecordSize
-	}
 	if recordLen == 0 {
 		if numRecords != 1 {
-			return &page, fmt.Errorf("unexpected number of records (%d)", numRecords)
+			return page, nil, fmt.Errorf("unexpected number of records (%d)", numRecords)
 		}
 		recordLen = len(v)
 	}
+	if numRecords < 0 || numRecords*recordLen > len(v) {
+		return page, nil, fmt.Errorf("%d records of length %d do not fit in page", numRecords, recordLen)
+	}
+	var crcErrors []CRCError
 	page.Records = make([][]byte, 0, numRecords)
 	// Collect records in reverse chronological order.
 	for i := numRecords - 1; i >= 0; i-- {
@@ -174,7 +427,7 @@ func UnmarshalPage(v []byte) (*PageInfo, error) {
 		rec = rec[:recordLen-2]
 		calc := crc16(rec)
 		if crc != calc {
-			return &page, CRCError{
+			e := CRCError{
 				Kind:       "record",
 				Received:   crc,
 				Computed:   calc,
@@ -182,10 +435,98 @@ func UnmarshalPage(v []byte) (*PageInfo, error) {
 				PageNumber: pageNumber,
 				Data:       rec,
 			}
+			if !lenient {
+				return page, nil, e
+			}
+			crcErrors = append(crcErrors, e)
+			continue
 		}
 		page.Records = append(page.Records, rec)
 	}
-	return &page, nil
+	return page, crcErrors, nil
+}
+
+// MarshalPage returns a page of the given type and number containing
+// the given records, which must be in reverse chronological order
+// (as returned by UnmarshalRecords).
+// The page uses the current record revision for its type,
+// and the FirstIndex field of its header is 0.
+func MarshalPage(pageType PageType, pageNumber int, records Records) ([]byte, error) {
+	data := make([][]byte, len(records))
+	for i, r := range records {
+		v, err := MarshalRecord(pageType, r)
+		if err != nil {
+			return nil, err
+		}
+		data[i] = v
+	}
+	recordLen := recordLength[pageType]
+	if recordLen == 0 {
+		if len(data) > 1 {
+			return nil, fmt.Errorf("unexpected number of %v records (%d)", pageType, len(data))
+		}
+		recordLen = pageDataSize
+	}
+	if len(data)*recordLen > pageDataSize {
+		return nil, fmt.Errorf("too many %v records for one page (%d)", pageType, len(data))
+	}
+	h := make([]byte, headerSize)
+	copy(h[4:8], marshalInt32(int32(len(data))))
+	h[8] = byte(pageType)
+	h[9] = pageRevision(pageType)
+	copy(h[10:14], marshalInt32(int32(pageNumber)))
+	copy(h[headerSize-2:], marshalUint16(crc16(h[:headerSize-2])))
+	page := make([]byte, pageDataSize)
+	for i := range page {
+		page[i] = 0xFF
+	}
+	// Store records in chronological order.
+	v := page
+	for i := len(data) - 1; i >= 0; i-- {
+		rec := data[i]
+		if len(rec)+2 > recordLen {
+			return nil, fmt.Errorf("%v record is too long (%d bytes)", pageType, len(rec))
+		}
+		copy(v, rec)
+		for j := len(rec); j < recordLen-2; j++ {
+			v[j] = 0
+		}
+		copy(v[recordLen-2:recordLen], marshalUint16(crc16(v[:recordLen-2])))
+		v = v[recordLen:]
+	}
+	return append(h, page...), nil
+}
+
+// UnmarshalPageHeader validates the CRC of the given page header
+// (which may be followed by the page data) and decodes it.
+func UnmarshalPageHeader(v []byte) (*PageInfo, error) {
+	if len(v) < headerSize {
+		return nil, fmt.Errorf("invalid page length (%d)", len(v))
+	}
+	h := v[:headerSize]
+	crc := unmarshalUint16(h[headerSize-2:])
+	calc := crc16(h[:headerSize-2])
+	if crc != calc {
+		return nil, CRCError{
+			Kind:     "page",
+			Received: crc,
+			Computed: calc,
+			PageType: InvalidPage,
+			Data:     h,
+		}
+	}
+	return &PageInfo{
+		FirstIndex: int(unmarshalInt32(h[0:4])),
+		NumRecords: int(unmarshalInt32(h[4:8])),
+		Type:       PageType(h[8]),
+		Revision:   h[9],
+		Number:     int(unmarshalInt32(h[10:14])),
+		Reserved: [3]int32{
+			unmarshalInt32(h[14:18]),
+			unmarshalInt32(h[18:22]),
+			unmarshalInt32(h[22:26]),
+		},
+	}, nil
 }
 
 // UnmarshalRecords unmarshals raw records into records of the appropriate type.
@@ -214,19 +555,57 @@ var IterationDone = errors.New("iteration done") // nolint
 // record in each page.  Pages are visited in reverse order to facilitate
 // scanning for recent records.
 func (cgm *CGM) IterRecords(pageType PageType, firstPage, lastPage int, recordFn RecordFunc) {
-	for n := lastPage; n >= firstPage; n-- {
-		records := cgm.ReadRecords(pageType, n)
-		if cgm.Error() != nil {
-			return
+	cgm.IterRecordsContext(context.Background(), pageType, firstPage, lastPage, recordFn)
+}
+
+// IterRecordsContext is like IterRecords, but it stops
+// when ctx is canceled or its deadline expires.
+func (cgm *CGM) IterRecordsContext(ctx context.Context, pageType PageType, firstPage, lastPage int, recordFn RecordFunc) {
+	if cgm.Error() != nil {
+		return
+	}
+	cgm.SetError(iterRecords(ctx, cgm, pageType, firstPage, lastPage, recordFn))
+}
+
+// IterRecordsE is like IterRecords, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) IterRecordsE(pageType PageType, firstPage, lastPage int, recordFn RecordFunc) error {
+	return iterRecords(context.Background(), cgm, pageType, firstPage, lastPage, recordFn)
+}
+
+func iterRecords(ctx context.Context, src pageReader, pageType PageType, firstPage, lastPage int, recordFn RecordFunc) error {
+	// Read the last page by itself, since iteration often stops there,
+	// and then as many pages per request as possible.
+	count := 1
+	for last := lastPage; last >= firstPage; last -= count {
+		if last < lastPage {
+			count = maxPagesPerRead
+		}
+		first := last - count + 1
+		if first < firstPage {
+			first = firstPage
 		}
-		for _, r := range records {
-			err := recordFn(r)
+		pages, err := src.readPages(ctx, pageType, first, last-first+1)
+		if err != nil {
+			return err
+		}
+		info, skipped := src.partitionInfo(ctx), src.lenient()
+		for i := len(pages) - 1; i >= 0; i-- {
+			n := first + i
+			records, err := pageRecords(info, skipped, pageType, n, pages[i])
 			if err != nil {
-				if err != IterationDone {
-					cgm.SetError(fmt.Errorf("%v page %d: %v", pageType, n, err))
+				return err
+			}
+			for _, r := range records {
+				err := recordFn(r)
+				if err != nil {
+					if err != IterationDone {
+						return fmt.Errorf("%v page %d: %v", pageType, n, err)
+					}
+					return nil
 				}
-				return
 			}
 		}
 	}
+	return nil
 }
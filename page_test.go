@@ -1,9 +1,14 @@
 package dexcom
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 type pageTestCase struct {
@@ -77,3 +82,395 @@ func checkRecords(t *testing.T, decoded Records, jsonFile string) {
 		t.Errorf("JSON is different:\n%s\n", msg)
 	}
 }
+
+func TestPageHeader(t *testing.T) {
+	c := pageTestCase{EGVData, 312, 0}
+	v, err := readPageFile(testFileName(c) + ".data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PageInfo{
+		Type:       EGVData,
+		Number:     312,
+		FirstIndex: 11856,
+		NumRecords: 23,
+		Revision:   2,
+	}
+	page, err := UnmarshalPageHeader(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*page, want) {
+		t.Errorf("UnmarshalPageHeader == %+v, want %+v", *page, want)
+	}
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	page = cgm.ReadPageHeader(EGVData, 312)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if !reflect.DeepEqual(*page, want) {
+		t.Errorf("ReadPageHeader == %+v, want %+v", *page, want)
+	}
+	full, err := UnmarshalPage(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.FirstIndex != want.FirstIndex || len(full.Records) != want.NumRecords {
+		t.Errorf("UnmarshalPage == %+v, want header %+v", *full, want)
+	}
+	_, err = cgm.ReadPageHeaderE(EGVData, 313)
+	if err == nil {
+		t.Errorf("ReadPageHeaderE(%v, 313) succeeded, want error", EGVData)
+	}
+}
+
+// countingConn counts the ReadDatabasePages requests sent to an emulator
+// and the pages they request.
+type countingConn struct {
+	*Emulator
+	pageReads int
+	pages     int
+}
+
+func (c *countingConn) Send(data []byte) error {
+	if Command(data[3]) == ReadDatabasePages {
+		c.pageReads++
+		c.pages += int(data[9])
+	}
+	return c.Emulator.Send(data)
+}
+
+func TestReadPages(t *testing.T) {
+	const first, count = 310, 7
+	t0 := parseTime("2017-09-17 11:00:00")
+	e := NewEmulator()
+	for n := first; n < first+count; n++ {
+		var records Records
+		for i := 1; i >= 0; i-- {
+			k := 2*(n-first) + i
+			records = append(records, testRecord(t0.Add(time.Duration(k)*5*time.Minute), &EGVInfo{Glucose: uint16(100 + k), Trend: Flat}))
+		}
+		err := e.AddPage(makePage(EGVData, n, records...))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn := &countingConn{Emulator: e}
+	cgm := &CGM{Connection: conn}
+	pages := cgm.ReadPages(EGVData, first, count)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(pages) != count {
+		t.Fatalf("ReadPages returned %d pages, want %d", len(pages), count)
+	}
+	for i, v := range pages {
+		page, err := UnmarshalPageHeader(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Number != first+i {
+			t.Errorf("page %d has number %d, want %d", i, page.Number, first+i)
+		}
+	}
+	want := (count + maxPagesPerRead - 1) / maxPagesPerRead
+	if conn.pageReads != want {
+		t.Errorf("ReadPages used %d requests, want %d", conn.pageReads, want)
+	}
+	conn.pageReads = 0
+	records := cgm.ReadHistory(EGVData, time.Time{})
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(records) != 2*count {
+		t.Fatalf("ReadHistory returned %d records, want %d", len(records), 2*count)
+	}
+	for i, r := range records {
+		if r.Glucose() != uint16(100+2*count-1-i) {
+			t.Errorf("record %d has glucose %d, want %d", i, r.Glucose(), 100+2*count-1-i)
+		}
+	}
+	// The last page is read by itself.
+	want = 1 + (count-1+maxPagesPerRead-1)/maxPagesPerRead
+	if conn.pageReads != want {
+		t.Errorf("ReadHistory used %d page requests, want %d", conn.pageReads, want)
+	}
+	conn.pages = 0
+	since := t0.Add(time.Duration(2*count-2) * 5 * time.Minute)
+	records = cgm.ReadHistory(EGVData, since)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(records) != 1 || conn.pages != 1 {
+		t.Errorf("ReadHistory since %v returned %d records and read %d pages, want 1 and 1", since, len(records), conn.pages)
+	}
+	_, err := cgm.ReadPagesE(EGVData, first+count-1, 2)
+	if err == nil {
+		t.Errorf("ReadPagesE past last page succeeded, want error")
+	}
+	_, err = cgm.ReadPagesE(EGVData, first, -1)
+	if err == nil {
+		t.Errorf("ReadPagesE with negative count succeeded, want error")
+	}
+	_, err = NewPageStore().readPages(context.Background(), EGVData, first, -1)
+	if err == nil {
+		t.Errorf("PageStore readPages with negative count succeeded, want error")
+	}
+}
+
+func TestMarshalPage(t *testing.T) {
+	cases := []pageTestCase{
+		{ManufacturingData, 0, 0},
+		{SensorData, 469, 0},
+		{EGVData, 312, 0},
+		{CalibrationData, 1432, 0},
+		{CalibrationData, 252, 0},
+		{CalibrationData, 845, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.pageType.String(), func(t *testing.T) {
+			v, err := readPageFile(testFileName(c) + ".data")
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, err := UnmarshalPage(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			records, err := UnmarshalRecords(c.pageType, page.Records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			marshalTest(t, c.pageType, c.pageNumber, records)
+			checkRecords(t, records, testFileName(c)+".json")
+		})
+	}
+}
+
+func TestMarshalRecords(t *testing.T) {
+	t0 := parseTime("2017-09-17 11:00:00")
+	t1 := parseTime("2017-09-17 11:00:03")
+	ts := Timestamp{SystemTime: t0, DisplayTime: t1}
+	cases := []struct {
+		pageType PageType
+		records  Records
+	}{
+		{InsertionTimeData, Records{
+			{Timestamp: ts, Info: &InsertionInfo{Event: Stopped}},
+			{Timestamp: ts, Info: &InsertionInfo{SystemTime: t0, Event: Started}},
+		}},
+		{MeterData, Records{
+			{Timestamp: ts, Info: &MeterInfo{Glucose: 123, MeterTime: t1}},
+		}},
+		{EGVData, Records{
+			{Timestamp: ts, Info: &EGVInfo{Glucose: 401, DisplayOnly: true, Noise: 3, Trend: DownDown}},
+			{Timestamp: ts, Info: &EGVInfo{Glucose: uint16(SensorNotCalibrated), Trend: NotComputable}},
+		}},
+		{UserEventData, Records{
+			{Timestamp: ts, Info: &UserEventInfo{Type: ExerciseEvent, SubType: byte(HeavyExercise), EventTime: t0, Value: 45}},
+		}},
+		{SoftwareData, Records{
+			{Timestamp: ts, Info: SoftwareInfo{"ApplicationName": "G4 <Share>", "ApplicationVersion": "1.0"}},
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.pageType.String(), func(t *testing.T) {
+			marshalTest(t, c.pageType, 17, c.records)
+		})
+	}
+}
+
+func marshalTest(t *testing.T, pageType PageType, pageNumber int, records Records) {
+	v, err := MarshalPage(pageType, pageNumber, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := UnmarshalPage(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Type != pageType || page.Number != pageNumber {
+		t.Errorf("marshaled page is %v page %d, want %v page %d", page.Type, page.Number, pageType, pageNumber)
+	}
+	decoded, err := UnmarshalRecords(pageType, page.Records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, records) {
+		t.Errorf("UnmarshalRecords(MarshalPage(%v)) == %+v, want %+v", pageType, decoded, records)
+	}
+	for _, r := range decoded {
+		if r.PageType() != pageType {
+			t.Errorf("PageType() == %v, want %v", r.PageType(), pageType)
+		}
+	}
+}
+
+func TestMarshalPageErrors(t *testing.T) {
+	ts := Timestamp{SystemTime: parseTime("2017-09-17 11:00:00")}
+	egv := Record{Timestamp: ts, Info: &EGVInfo{Glucose: 100}}
+	tooMany := make(Records, pageDataSize/recordLength[EGVData]+1)
+	for i := range tooMany {
+		tooMany[i] = egv
+	}
+	cases := []struct {
+		pageType PageType
+		records  Records
+	}{
+		{EGVData, Records{{Timestamp: ts}}},
+		{EGVData, tooMany},
+		{SensorData, Records{egv}},
+		{ManufacturingData, Records{
+			{Timestamp: ts, Info: ManufacturingInfo{}},
+			{Timestamp: ts, Info: ManufacturingInfo{}},
+		}},
+		{InvalidPage, Records{egv}},
+	}
+	for _, c := range cases {
+		_, err := MarshalPage(c.pageType, 1, c.records)
+		if err == nil {
+			t.Errorf("MarshalPage(%v, %d records) succeeded, want error", c.pageType, len(c.records))
+		}
+	}
+}
+
+func TestUnmarshalPageLenient(t *testing.T) {
+	v, err := readPageFile(testFileName(pageTestCase{EGVData, 312, 0}) + ".data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := UnmarshalPage(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := page.NumRecords
+	// Corrupt the glucose value of the third record.
+	recordLen := recordLength[EGVData]
+	v[headerSize+2*recordLen+8] ^= 0x40
+	_, err = UnmarshalPage(v)
+	if _, isCRCError := err.(CRCError); !isCRCError {
+		t.Errorf("UnmarshalPage of corrupted page returned %v, want CRC error", err)
+	}
+	page, crcErrors, err := UnmarshalPageLenient(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Records) != n-1 {
+		t.Errorf("UnmarshalPageLenient returned %d records, want %d", len(page.Records), n-1)
+	}
+	if len(crcErrors) != 1 {
+		t.Fatalf("UnmarshalPageLenient returned %d CRC errors, want 1", len(crcErrors))
+	}
+	if crcErrors[0].PageType != EGVData || crcErrors[0].PageNumber != 312 {
+		t.Errorf("CRC error is for %v page %d, want %v page 312", crcErrors[0].PageType, crcErrors[0].PageNumber, EGVData)
+	}
+
+	e := NewEmulator()
+	err = e.AddPage(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cgm := &CGM{Connection: e}
+	_, err = cgm.ReadRecordsE(EGVData, 312)
+	if err == nil {
+		t.Errorf("ReadRecordsE of corrupted page succeeded, want error")
+	}
+	var skipped []CRCError
+	cgm.SetLenient(func(e CRCError) { skipped = append(skipped, e) })
+	records, err := cgm.ReadRecordsE(EGVData, 312)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || skipped[0].PageNumber != 312 {
+		t.Errorf("lenient ReadRecordsE skipped %v, want 1 record in page 312", skipped)
+	}
+	if len(records) != n-1 {
+		t.Errorf("lenient ReadRecordsE returned %d records, want %d", len(records), n-1)
+	}
+}
+
+func TestUnmarshalPageTooManyRecords(t *testing.T) {
+	v := makePage(EGVData, 1, testRecord(parseTime("2017-09-17 11:00:00"), &EGVInfo{Glucose: 100, Trend: Flat}))
+	copy(v[4:8], marshalInt32(int32(pageDataSize/recordLength[EGVData]+1)))
+	copy(v[headerSize-2:headerSize], marshalUint16(crc16(v[:headerSize-2])))
+	for _, lenient := range []bool{false, true} {
+		_, _, err := (*PartitionInfo)(nil).unmarshalPage(v, lenient)
+		if err == nil {
+			t.Errorf("unmarshalPage(lenient = %v) succeeded, want error", lenient)
+		}
+	}
+}
+
+func TestUnknownRecordLength(t *testing.T) {
+	t0 := parseTime("2017-09-17 11:00:00")
+	v := makePage(EGVData, 0, testRecord(t0.Add(5*time.Minute), &EGVInfo{Glucose: 101}), testRecord(t0, &EGVInfo{Glucose: 100}))
+	// Relabel the page as a type with no built-in record length.
+	v[8], v[9] = byte(DeviationData), 1
+	copy(v[headerSize-2:headerSize], marshalUint16(crc16(v[:headerSize-2])))
+	_, err := UnmarshalPage(v)
+	if err == nil {
+		t.Errorf("UnmarshalPage of %v page succeeded, want error", DeviationData)
+	}
+	// The partition info can supply the record length.
+	info := &PartitionInfo{
+		PageDataLength: pageDataSize,
+		Partitions:     []Partition{{ID: DeviationData, RecordRevision: 1, RecordLength: recordLength[EGVData]}},
+	}
+	page, err := info.UnmarshalPage(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Records) != 2 {
+		t.Errorf("UnmarshalPage returned %d records, want 2", len(page.Records))
+	}
+}
+
+func TestRecordInfo(t *testing.T) {
+	ts := Timestamp{SystemTime: parseTime("2017-09-17 11:00:00"), DisplayTime: parseTime("2017-09-17 10:59:00")}
+	cases := []struct {
+		r        Record
+		pageType PageType
+	}{
+		{Record{Timestamp: ts}, InvalidPage},
+		{Record{Timestamp: ts, Info: &EGVInfo{Glucose: 100, Trend: Flat}}, EGVData},
+		{Record{Timestamp: ts, Info: ManufacturingInfo{"SerialNumber": "SM44792675"}}, ManufacturingData},
+		{Record{Timestamp: ts, Info: FirmwareInfo{"FirmwareVersion": "4.0"}}, FirmwareData},
+		{Record{Timestamp: ts, Info: SoftwareInfo{"Owner": "Emulator"}}, SoftwareData},
+	}
+	for _, c := range cases {
+		if c.r.PageType() != c.pageType {
+			t.Errorf("%+v.PageType() == %v, want %v", c.r, c.r.PageType(), c.pageType)
+		}
+		isXML := c.pageType <= SoftwareData
+		if (c.r.XML() != nil) != isXML {
+			t.Errorf("%+v.XML() == %v", c.r, c.r.XML())
+		}
+		v, err := json.Marshal(c.r)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		var r Record
+		err = json.Unmarshal(v, &r)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		want := c.r.Info
+		if isXML {
+			// The JSON encoding of XML records does not include the page type.
+			if !bytes.Contains(v, []byte(`"XML":`)) {
+				t.Errorf("JSON %s does not contain an XML field", v)
+			}
+			want = ManufacturingInfo(c.r.XML())
+		}
+		if !r.Timestamp.SystemTime.Equal(ts.SystemTime) || !r.Timestamp.DisplayTime.Equal(ts.DisplayTime) || !reflect.DeepEqual(r.Info, want) {
+			t.Errorf("JSON %s decoded as %+v, want %+v", v, r.Info, want)
+		}
+	}
+	var r Record
+	err := json.Unmarshal([]byte(`{"EGV": {}, "Sensor": {}}`), &r)
+	if err == nil {
+		t.Errorf("decoding record with two types succeeded, want error")
+	}
+}
@@ -0,0 +1,112 @@
+package dexcom
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+)
+
+// PartitionInfo describes the layout of the receiver's database,
+// as returned by the ReadDatabasePartitionInfo command.
+type PartitionInfo struct {
+	SchemaVersion     int         `xml:",attr"`
+	PageHeaderVersion int         `xml:",attr"`
+	PageDataLength    int         `xml:",attr"`
+	Partitions        []Partition `xml:"Partition"`
+}
+
+// Partition describes the records stored in pages of one type.
+// The record length includes the 2-byte CRC.
+type Partition struct {
+	Name           string   `xml:",attr"`
+	ID             PageType `xml:"Id,attr"`
+	RecordRevision int      `xml:",attr"`
+	RecordLength   int      `xml:",attr"`
+}
+
+// UnmarshalPartitionInfo parses the XML returned by the ReadDatabasePartitionInfo command.
+func UnmarshalPartitionInfo(v []byte) (*PartitionInfo, error) {
+	info := &PartitionInfo{}
+	err := xml.Unmarshal(v, info)
+	if err != nil {
+		return nil, fmt.Errorf("invalid partition info: %v", err)
+	}
+	return info, nil
+}
+
+// Partition returns the description of the given page type's partition.
+func (info *PartitionInfo) Partition(pageType PageType) (Partition, bool) {
+	if info != nil {
+		for _, p := range info.Partitions {
+			if p.ID == pageType {
+				return p, true
+			}
+		}
+	}
+	return Partition{}, false
+}
+
+// recordLength returns the length of records in a page with the given
+// type and record revision, if the partition info describes it.
+// As in the recordLength table, 0 means a single record that fills the page.
+func (info *PartitionInfo) recordLength(pageType PageType, rev byte) (int, bool) {
+	p, found := info.Partition(pageType)
+	if !found || p.RecordRevision != int(rev) || p.RecordLength <= 0 {
+		return 0, false
+	}
+	dataLen := info.PageDataLength
+	if dataLen <= 0 {
+		// The page data length is missing or invalid.
+		dataLen = pageDataSize
+	}
+	if p.RecordLength >= dataLen {
+		return 0, true
+	}
+	return p.RecordLength, true
+}
+
+// ReadPartitionInfo gets the database partition info from the Dexcom CGM receiver.
+func (cgm *CGM) ReadPartitionInfo() *PartitionInfo {
+	if cgm.Error() != nil {
+		return nil
+	}
+	info, err := cgm.ReadPartitionInfoE()
+	cgm.SetError(err)
+	return info
+}
+
+// ReadPartitionInfoE is like ReadPartitionInfo, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadPartitionInfoE() (*PartitionInfo, error) {
+	return cgm.readPartitionInfo(context.Background())
+}
+
+func (cgm *CGM) readPartitionInfo(ctx context.Context) (*PartitionInfo, error) {
+	v, err := cgm.cmd(ctx, ReadDatabasePartitionInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalPartitionInfo(v)
+}
+
+// partitionInfo returns the receiver's partition info, reading it
+// the first time it is needed.  If it cannot be read, the built-in
+// record lengths are used instead.
+func (cgm *CGM) partitionInfo(ctx context.Context) *PartitionInfo {
+	cgm.infoMu.Lock()
+	defer cgm.infoMu.Unlock()
+	if !cgm.infoRead {
+		info, err := cgm.readPartitionInfo(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Try again next time.
+				return nil
+			}
+			log.Printf("using built-in record lengths: %v", err)
+		}
+		cgm.info = info
+		cgm.infoRead = true
+	}
+	return cgm.info
+}
@@ -0,0 +1,105 @@
+package dexcom
+
+import (
+	"testing"
+)
+
+func TestReadPartitionInfo(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	info := cgm.ReadPartitionInfo()
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if info.PageDataLength != pageDataSize {
+		t.Errorf("PageDataLength == %d, want %d", info.PageDataLength, pageDataSize)
+	}
+	p, found := info.Partition(EGVData)
+	if !found || p.Name != "EGVData" || p.RecordRevision != 2 || p.RecordLength != 13 {
+		t.Errorf("Partition(%v) == %+v, %v", EGVData, p, found)
+	}
+	_, found = info.Partition(DeviationData)
+	if found {
+		t.Errorf("Partition(%v) found, want not found", DeviationData)
+	}
+}
+
+func TestPartitionRecordLength(t *testing.T) {
+	c := pageTestCase{EGVData, 312, 0}
+	v, err := readPageFile(testFileName(c) + ".data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := func(rev, length int) *PartitionInfo {
+		return &PartitionInfo{
+			PageDataLength: pageDataSize,
+			Partitions:     []Partition{{ID: EGVData, RecordRevision: rev, RecordLength: length}},
+		}
+	}
+	_, err = info(2, 13).UnmarshalPage(v)
+	if err != nil {
+		t.Errorf("UnmarshalPage with matching partition info: %v", err)
+	}
+	// The partition info is used when its revision matches the page.
+	_, err = info(2, 14).UnmarshalPage(v)
+	if err == nil {
+		t.Errorf("UnmarshalPage with wrong record length succeeded, want error")
+	}
+	// Otherwise the built-in record length is used.
+	_, err = info(3, 14).UnmarshalPage(v)
+	if err != nil {
+		t.Errorf("UnmarshalPage with other revision: %v", err)
+	}
+}
+
+func TestPartitionInfoNoPageDataLength(t *testing.T) {
+	info, err := UnmarshalPartitionInfo([]byte(`<PartitionInfo SchemaVersion="1" PageHeaderVersion="1">` +
+		`<Partition Name="ManufacturingData" Id="0" RecordRevision="1" RecordLength="500" />` +
+		`<Partition Name="EGVData" Id="4" RecordRevision="2" RecordLength="13" />` +
+		`</PartitionInfo>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PageDataLength != 0 {
+		t.Errorf("PageDataLength == %d, want 0", info.PageDataLength)
+	}
+	for _, c := range []pageTestCase{{ManufacturingData, 0, 0}, {EGVData, 312, 0}} {
+		v, err := readPageFile(testFileName(c) + ".data")
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := info.UnmarshalPage(v)
+		if err != nil {
+			t.Errorf("UnmarshalPage(%v) without PageDataLength: %v", c.pageType, err)
+			continue
+		}
+		records, err := UnmarshalRecords(c.pageType, page.Records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRecords(t, records, testFileName(c)+".json")
+	}
+}
+
+// noPartitionInfo is a Connection for a receiver
+// that does not support the ReadDatabasePartitionInfo command.
+type noPartitionInfo struct {
+	*Emulator
+}
+
+func (c noPartitionInfo) Send(data []byte) error {
+	if Command(data[3]) == ReadDatabasePartitionInfo {
+		data = marshalPacket(Command(0xFE), nil)
+	}
+	return c.Emulator.Send(data)
+}
+
+func TestPartitionInfoFallback(t *testing.T) {
+	cgm := &CGM{Connection: noPartitionInfo{newTestEmulator(t)}}
+	for _, c := range emulatorPages {
+		records := cgm.ReadRecords(c.pageType, c.pageNumber)
+		if cgm.Error() != nil {
+			t.Fatal(cgm.Error())
+		}
+		checkRecords(t, records, testFileName(c)+".json")
+	}
+}
@@ -0,0 +1,92 @@
+package dexcom
+
+import (
+	"sync"
+	"time"
+)
+
+// byteQueue holds the data received in the background by a connection.
+// Receiving from it fails if no data arrives within the timeout
+// or if the deadline passes first.
+type byteQueue struct {
+	c       chan byte
+	err     error // error that ended the data, valid once c is closed
+	timeout time.Duration
+
+	mu       sync.Mutex // protects deadline
+	deadline time.Time
+	wake     chan struct{} // signaled when the deadline changes
+}
+
+func newByteQueue(timeout time.Duration) *byteQueue {
+	return &byteQueue{
+		c:       make(chan byte, 1600),
+		timeout: timeout,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// close ends the data with the given error.
+func (q *byteQueue) close(err error) {
+	q.err = err
+	close(q.c)
+}
+
+// SetDeadline sets the time after which receiving fails.
+// A zero time means no deadline.
+func (q *byteQueue) SetDeadline(t time.Time) error {
+	q.mu.Lock()
+	q.deadline = t
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// wait returns a channel that receives a value when the timeout
+// or the deadline expires, whichever is first.
+func (q *byteQueue) wait() <-chan time.Time {
+	d := q.timeout
+	q.mu.Lock()
+	if !q.deadline.IsZero() {
+		if until := time.Until(q.deadline); until < d {
+			d = until
+		}
+	}
+	q.mu.Unlock()
+	return time.After(d)
+}
+
+func (q *byteQueue) receive(data []byte) error {
+	for i := 0; i < len(data); {
+		select {
+		case b, ok := <-q.c:
+			if !ok {
+				return q.err
+			}
+			data[i] = b
+			i++
+		case <-q.wake:
+		case <-q.wait():
+			return errReceiveTimeout
+		}
+	}
+	return nil
+}
+
+// drain discards the data in the queue, and any that arrives until
+// the queue has been empty for the given time.
+func (q *byteQueue) drain(quiet time.Duration) {
+	for {
+		select {
+		case _, ok := <-q.c:
+			if !ok {
+				return
+			}
+		case <-time.After(quiet):
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package dexcom
+
+import (
+	"time"
+)
+
+// Glucose converts a raw sensor count to a glucose value in mg/dL
+// using the calibration's slope, intercept, and scale.
+// It returns 0 if the count is 0 or the calibration is unusable.
+func (cal *CalibrationInfo) Glucose(count uint32) float64 {
+	if count == 0 || cal.Slope == 0 || cal.Scale == 0 {
+		return 0
+	}
+	return cal.Scale * (float64(count) - cal.Intercept) / cal.Slope
+}
+
+// RawGlucose represents glucose values computed from a sensor record.
+// Glucose values are in mg/dL.
+type RawGlucose struct {
+	Timestamp  Timestamp
+	Unfiltered float64 // computed from the unfiltered count
+	Filtered   float64 // computed from the filtered count
+	RawBG      float64
+	EGV        uint16 // receiver's glucose value for the same reading, or 0 if none
+}
+
+// Maximum difference between the times of a sensor record
+// and the EGV record for the same reading.
+const egvMatchWindow = time.Minute
+
+// RawGlucoseValues applies the most recent calibration in effect at the time
+// of each sensor record and returns the resulting glucose values.
+// The records of each type must be most recent first, as returned by ReadHistory
+// (they may be merged by MergeHistory), and should include the sensor, EGV,
+// and calibration records for the same period.
+// Sensor records with no earlier calibration are omitted.
+// Records are matched by system time, which is unaffected
+// by changes to the receiver's display time.
+//
+// RawBG is computed as by Nightscout: when the receiver's EGV is a normal
+// glucose value, the unfiltered value is scaled by the ratio of the EGV
+// to the filtered value; otherwise (for example, during SpecialGlucose
+// periods) it is the unfiltered value.
+func RawGlucoseValues(records Records) []RawGlucose {
+	var sensors, egvs, cals Records
+	for _, r := range records {
+		switch info := r.Info.(type) {
+		case *SensorInfo:
+			sensors = append(sensors, r)
+		case *EGVInfo:
+			egvs = append(egvs, r)
+		case *CalibrationInfo:
+			if info.Slope != 0 && info.Scale != 0 {
+				cals = append(cals, r)
+			}
+		}
+	}
+	var results []RawGlucose
+	j, k := 0, 0
+	for _, r := range sensors {
+		t := r.Timestamp.SystemTime
+		for k < len(cals) && cals[k].Timestamp.SystemTime.After(t) {
+			k++
+		}
+		if k == len(cals) {
+			break
+		}
+		cal := cals[k].Info.(*CalibrationInfo)
+		sensor := r.Info.(*SensorInfo)
+		for j < len(egvs) && egvs[j].Timestamp.SystemTime.After(t.Add(egvMatchWindow)) {
+			j++
+		}
+		g := RawGlucose{
+			Timestamp:  r.Timestamp,
+			Unfiltered: cal.Glucose(sensor.Unfiltered),
+			Filtered:   cal.Glucose(sensor.Filtered),
+		}
+		if j < len(egvs) && !egvs[j].Timestamp.SystemTime.Before(t.Add(-egvMatchWindow)) {
+			g.EGV = egvs[j].Glucose()
+		}
+		g.RawBG = g.Unfiltered
+		if g.EGV != 0 && !IsSpecial(g.EGV) && g.Filtered > 0 {
+			g.RawBG = g.Unfiltered * float64(g.EGV) / g.Filtered
+		}
+		results = append(results, g)
+	}
+	return results
+}
@@ -0,0 +1,67 @@
+package dexcom
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRawGlucoseValues(t *testing.T) {
+	s := decodeRecords(fmt.Sprintf("%s/sensor.json", testDataDir))
+	e := decodeRecords(fmt.Sprintf("%s/egv.json", testDataDir))
+	c := decodeRecords(fmt.Sprintf("%s/5.845.json", testDataDir))
+	values := RawGlucoseValues(MergeHistory(s, e, c))
+	if len(values) != len(s) {
+		t.Fatalf("RawGlucoseValues returned %d values, want %d", len(values), len(s))
+	}
+	cal := c[0].Info.(*CalibrationInfo)
+	for i, g := range values {
+		if g.EGV != e[i].Glucose() {
+			t.Errorf("value %d has EGV %d, want %d", i, g.EGV, e[i].Glucose())
+		}
+		unfiltered := cal.Scale * (float64(s[i].Info.(*SensorInfo).Unfiltered) - cal.Intercept) / cal.Slope
+		if math.Abs(g.Unfiltered-unfiltered) > 1e-9 {
+			t.Errorf("value %d has unfiltered glucose %g, want %g", i, g.Unfiltered, unfiltered)
+		}
+		rawBG := g.Unfiltered * float64(g.EGV) / g.Filtered
+		if math.Abs(g.RawBG-rawBG) > 1e-9 {
+			t.Errorf("value %d has raw BG %g, want %g", i, g.RawBG, rawBG)
+		}
+	}
+	if math.Abs(values[0].Unfiltered-151.579) > 0.001 {
+		t.Errorf("first unfiltered glucose is %g, want 151.579", values[0].Unfiltered)
+	}
+}
+
+func TestRawGlucoseSpecial(t *testing.T) {
+	t0 := parseTime("2017-09-17 11:00:00")
+	ts := func(d time.Duration) Timestamp {
+		return Timestamp{SystemTime: t0.Add(d), DisplayTime: t0.Add(d)}
+	}
+	cal := &CalibrationInfo{Slope: 1000, Intercept: 30000, Scale: 1}
+	records := Records{
+		{Timestamp: ts(10 * time.Minute), Info: &SensorInfo{Unfiltered: 150000, Filtered: 140000}},
+		{Timestamp: ts(10*time.Minute + time.Second), Info: &EGVInfo{Glucose: uint16(SensorNotCalibrated)}},
+		{Timestamp: ts(5 * time.Minute), Info: &SensorInfo{Unfiltered: 130000, Filtered: 130000}},
+		{Timestamp: ts(0), Info: cal},
+		{Timestamp: ts(-5 * time.Minute), Info: &SensorInfo{Unfiltered: 120000, Filtered: 120000}},
+	}
+	values := RawGlucoseValues(records)
+	if len(values) != 2 {
+		t.Fatalf("RawGlucoseValues returned %d values, want 2", len(values))
+	}
+	cases := []struct {
+		egv                       uint16
+		unfiltered, filtered, raw float64
+	}{
+		{uint16(SensorNotCalibrated), 120, 110, 120},
+		{0, 100, 100, 100},
+	}
+	for i, c := range cases {
+		g := values[i]
+		if g.EGV != c.egv || g.Unfiltered != c.unfiltered || g.Filtered != c.filtered || g.RawBG != c.raw {
+			t.Errorf("value %d == %+v, want EGV %d, unfiltered %g, filtered %g, raw BG %g", i, g, c.egv, c.unfiltered, c.filtered, c.raw)
+		}
+	}
+}
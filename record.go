@@ -2,20 +2,23 @@ package dexcom
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"time"
 )
 
 type (
 	// Record represents a time-stamped Dexcom receiver record.
 	Record struct {
-		Timestamp   Timestamp
-		XML         XMLInfo          `json:",omitempty"`
-		Sensor      *SensorInfo      `json:",omitempty"`
-		EGV         *EGVInfo         `json:",omitempty"`
-		Calibration *CalibrationInfo `json:",omitempty"`
-		Insertion   *InsertionInfo   `json:",omitempty"`
-		Meter       *MeterInfo       `json:",omitempty"`
+		Timestamp Timestamp
+		Info      RecordInfo
+	}
+
+	// RecordInfo is the type-specific information in a Record.
+	// Each page type has its own RecordInfo type, listed in recordTypes.
+	RecordInfo interface {
+		isRecordInfo()
 	}
 
 	// Records represents a sequence of records.
@@ -67,6 +70,124 @@ type (
 	}
 )
 
+func (ManufacturingInfo) isRecordInfo() {}
+func (FirmwareInfo) isRecordInfo()      {}
+func (SoftwareInfo) isRecordInfo()      {}
+func (*SensorInfo) isRecordInfo()       {}
+func (*EGVInfo) isRecordInfo()          {}
+func (*CalibrationInfo) isRecordInfo()  {}
+func (*InsertionInfo) isRecordInfo()    {}
+func (*MeterInfo) isRecordInfo()        {}
+func (*UserEventInfo) isRecordInfo()    {}
+
+// recordType describes the records stored in pages of a given type.
+type recordType struct {
+	name      string     // name of the RecordInfo in JSON
+	info      RecordInfo // zero value of the RecordInfo type
+	unmarshal func(*Record, []byte)
+	marshal   func(*Record) []byte
+}
+
+var recordTypes = map[PageType]recordType{
+	ManufacturingData: {"XML", ManufacturingInfo(nil), unmarshalManufacturingInfo, marshalManufacturingInfo},
+	FirmwareData:      {"XML", FirmwareInfo(nil), unmarshalFirmwareInfo, marshalFirmwareInfo},
+	SoftwareData:      {"XML", SoftwareInfo(nil), unmarshalSoftwareInfo, marshalSoftwareInfo},
+	SensorData:        {"Sensor", (*SensorInfo)(nil), unmarshalSensorInfo, marshalSensorInfo},
+	EGVData:           {"EGV", (*EGVInfo)(nil), umarshalEGVInfo, marshalEGVInfo},
+	CalibrationData:   {"Calibration", (*CalibrationInfo)(nil), unmarshalCalibrationInfo, marshalCalibrationInfo},
+	InsertionTimeData: {"Insertion", (*InsertionInfo)(nil), unmarshalInsertionInfo, marshalInsertionInfo},
+	MeterData:         {"Meter", (*MeterInfo)(nil), unmarshalMeterInfo, marshalMeterInfo},
+	UserEventData:     {"UserEvent", (*UserEventInfo)(nil), unmarshalUserEventInfo, marshalUserEventInfo},
+}
+
+// Page types indexed by RecordInfo type and by JSON name.
+// All XML records share the JSON name "XML", which does not identify
+// the page type, so it is decoded as ManufacturingData.
+var (
+	infoPageType = make(map[reflect.Type]PageType)
+	namePageType = make(map[string]PageType)
+)
+
+func init() {
+	for pageType, t := range recordTypes {
+		infoPageType[reflect.TypeOf(t.info)] = pageType
+		if t.name != "XML" || pageType == ManufacturingData {
+			namePageType[t.name] = pageType
+		}
+	}
+}
+
+// PageType returns the type of page that the record belongs to,
+// or InvalidPage if the record has no type-specific information.
+func (r Record) PageType() PageType {
+	pageType, found := infoPageType[reflect.TypeOf(r.Info)]
+	if !found {
+		return InvalidPage
+	}
+	return pageType
+}
+
+// MarshalJSON encodes a record as a JSON object with the timestamp
+// and the type-specific information, named as in recordTypes.
+func (r Record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"Timestamp":`)
+	v, err := json.Marshal(r.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(v)
+	if r.Info != nil {
+		pageType := r.PageType()
+		if pageType == InvalidPage {
+			return nil, fmt.Errorf("unknown record info type %T", r.Info)
+		}
+		v, err = json.Marshal(r.Info)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`,"` + recordTypes[pageType].name + `":`)
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a record encoded by MarshalJSON.
+// XML records are decoded as ManufacturingInfo,
+// since their JSON encoding does not include the page type.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+	*r = Record{}
+	for name, v := range fields {
+		if name == "Timestamp" {
+			err = json.Unmarshal(v, &r.Timestamp)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		pageType, found := namePageType[name]
+		if !found {
+			return fmt.Errorf("unknown record field %q", name)
+		}
+		if r.Info != nil {
+			return fmt.Errorf("record has both %v and %v information", r.PageType(), pageType)
+		}
+		p := reflect.New(reflect.TypeOf(recordTypes[pageType].info))
+		err = json.Unmarshal(v, p.Interface())
+		if err != nil {
+			return err
+		}
+		r.Info = p.Elem().Interface().(RecordInfo)
+	}
+	return nil
+}
+
 // Time returns the record's display time.
 func (r Record) Time() time.Time {
 	return r.Timestamp.DisplayTime
@@ -74,7 +195,7 @@ func (r Record) Time() time.Time {
 
 // Glucose returns the glucose field from an EGV record.
 func (r Record) Glucose() uint16 {
-	return r.EGV.Glucose
+	return r.Info.(*EGVInfo).Glucose
 }
 
 // Len returns the number of records.
@@ -87,29 +208,36 @@ func (v Records) Time(i int) time.Time {
 	return v[i].Time()
 }
 
-var recordUnmarshal = map[PageType]func(*Record, []byte){
-	ManufacturingData: umarshalXMLInfo,
-	FirmwareData:      umarshalXMLInfo,
-	SoftwareData:      umarshalXMLInfo,
-	SensorData:        unmarshalSensorInfo,
-	EGVData:           umarshalEGVInfo,
-	CalibrationData:   unmarshalCalibrationInfo,
-	InsertionTimeData: unmarshalInsertionInfo,
-	MeterData:         unmarshalMeterInfo,
-}
-
 func (r *Record) unmarshal(pageType PageType, v []byte) error {
-	f, found := recordUnmarshal[pageType]
+	t, found := recordTypes[pageType]
 	if !found {
 		return fmt.Errorf("unmarshaling of %v records is unimplemented: % X", pageType, v)
 	}
 	r.Timestamp.unmarshal(v[0:8])
-	f(r, v)
+	t.unmarshal(r, v)
 	return nil
 }
 
+// MarshalRecord returns the binary representation of a record
+// of the given type, without its CRC.
+// Fields that are not decoded by UnmarshalRecords are set to 0.
+func MarshalRecord(pageType PageType, r Record) ([]byte, error) {
+	t, found := recordTypes[pageType]
+	if !found {
+		return nil, fmt.Errorf("marshaling of %v records is unimplemented", pageType)
+	}
+	var v []byte
+	if r.PageType() == pageType {
+		v = t.marshal(&r)
+	}
+	if v == nil {
+		return nil, fmt.Errorf("record has no %v information", pageType)
+	}
+	return v, nil
+}
+
 func unmarshalSensorInfo(r *Record, v []byte) {
-	r.Sensor = &SensorInfo{
+	r.Info = &SensorInfo{
 		Unfiltered: unmarshalUint32(v[8:12]),
 		Filtered:   unmarshalUint32(v[12:16]),
 		RSSI:       int8(v[16]),
@@ -117,6 +245,17 @@ func unmarshalSensorInfo(r *Record, v []byte) {
 	}
 }
 
+func marshalSensorInfo(r *Record) []byte {
+	s := r.Info.(*SensorInfo)
+	if s == nil {
+		return nil
+	}
+	v := r.Timestamp.marshal()
+	v = append(v, marshalUint32(s.Unfiltered)...)
+	v = append(v, marshalUint32(s.Filtered)...)
+	return append(v, byte(s.RSSI), s.Unknown)
+}
+
 // SpecialGlucose represents a glucose value that indicates an exceptional condition.
 type SpecialGlucose uint16
 
@@ -186,7 +325,7 @@ const (
 
 func umarshalEGVInfo(r *Record, v []byte) {
 	g := unmarshalUint16(v[8:10])
-	r.EGV = &EGVInfo{
+	r.Info = &EGVInfo{
 		Glucose:     g & EGVValueMask,
 		DisplayOnly: g&EGVDisplayOnly != 0,
 		Noise:       v[10] & EGVNoiseMask >> 4,
@@ -194,6 +333,19 @@ func umarshalEGVInfo(r *Record, v []byte) {
 	}
 }
 
+func marshalEGVInfo(r *Record) []byte {
+	e := r.Info.(*EGVInfo)
+	if e == nil {
+		return nil
+	}
+	g := e.Glucose & EGVValueMask
+	if e.DisplayOnly {
+		g |= EGVDisplayOnly
+	}
+	v := append(r.Timestamp.marshal(), marshalUint16(g)...)
+	return append(v, e.Noise<<4&EGVNoiseMask|byte(e.Trend)&EGVTrendMask)
+}
+
 func unmarshalCalibrationInfo(r *Record, v []byte) {
 	cal := &CalibrationInfo{
 		Slope:     unmarshalFloat64(v[8:16]),
@@ -211,7 +363,7 @@ func unmarshalCalibrationInfo(r *Record, v []byte) {
 		cal.Data[i].TimeApplied = cal.Data[i].TimeApplied.Add(offset)
 		v = v[17:]
 	}
-	r.Calibration = cal
+	r.Info = cal
 }
 
 func (r *CalibrationRecord) unmarshal(v []byte) {
@@ -221,6 +373,35 @@ func (r *CalibrationRecord) unmarshal(v []byte) {
 	r.TimeApplied = unmarshalTime(v[12:16])
 }
 
+func marshalCalibrationInfo(r *Record) []byte {
+	cal := r.Info.(*CalibrationInfo)
+	if cal == nil {
+		return nil
+	}
+	v := r.Timestamp.marshal()
+	v = append(v, marshalFloat64(cal.Slope)...)
+	v = append(v, marshalFloat64(cal.Intercept)...)
+	v = append(v, marshalFloat64(cal.Scale)...)
+	v = append(v, 0, 0, 0)
+	v = append(v, marshalFloat64(cal.Decay)...)
+	v = append(v, byte(len(cal.Data)))
+	offset := r.Timestamp.DisplayTime.Sub(r.Timestamp.SystemTime)
+	for _, d := range cal.Data {
+		d.TimeEntered = d.TimeEntered.Add(-offset)
+		d.TimeApplied = d.TimeApplied.Add(-offset)
+		v = append(v, d.marshal()...)
+	}
+	return v
+}
+
+func (r CalibrationRecord) marshal() []byte {
+	v := marshalTime(r.TimeEntered)
+	v = append(v, marshalInt32(r.Glucose)...)
+	v = append(v, marshalInt32(r.Raw)...)
+	v = append(v, marshalTime(r.TimeApplied)...)
+	return append(v, 0)
+}
+
 // SensorChange represents a sensor change.
 type SensorChange byte
 
@@ -242,15 +423,38 @@ func unmarshalInsertionInfo(r *Record, v []byte) {
 	if !bytes.Equal(u, invalidTime) {
 		t = unmarshalTime(u)
 	}
-	r.Insertion = &InsertionInfo{
+	r.Info = &InsertionInfo{
 		SystemTime: t,
 		Event:      SensorChange(v[12]),
 	}
 }
 
+func marshalInsertionInfo(r *Record) []byte {
+	ins := r.Info.(*InsertionInfo)
+	if ins == nil {
+		return nil
+	}
+	v := r.Timestamp.marshal()
+	if ins.SystemTime.IsZero() {
+		v = append(v, invalidTime...)
+	} else {
+		v = append(v, marshalTime(ins.SystemTime)...)
+	}
+	return append(v, byte(ins.Event))
+}
+
 func unmarshalMeterInfo(r *Record, v []byte) {
-	r.Meter = &MeterInfo{
+	r.Info = &MeterInfo{
 		Glucose:   unmarshalUint16(v[8:10]),
 		MeterTime: unmarshalTime(v[10:14]),
 	}
 }
+
+func marshalMeterInfo(r *Record) []byte {
+	m := r.Info.(*MeterInfo)
+	if m == nil {
+		return nil
+	}
+	v := append(r.Timestamp.marshal(), marshalUint16(m.Glucose)...)
+	return append(v, marshalTime(m.MeterTime)...)
+}
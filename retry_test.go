@@ -0,0 +1,230 @@
+package dexcom
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// flakyConn wraps an Emulator and corrupts the first few
+// packets sent to it or bytes received from it.
+type flakyConn struct {
+	*Emulator
+	badSends    int
+	badReceives int
+}
+
+func (conn *flakyConn) Send(data []byte) error {
+	if conn.badSends > 0 {
+		conn.badSends--
+		data = append([]byte(nil), data...)
+		data[len(data)-1] ^= 0xFF
+	}
+	return conn.Emulator.Send(data)
+}
+
+func (conn *flakyConn) Receive(data []byte) error {
+	err := conn.Emulator.Receive(data)
+	if err == nil && conn.badReceives > 0 {
+		conn.badReceives--
+		data[0] ^= 0xFF
+	}
+	return err
+}
+
+func TestRetry(t *testing.T) {
+	cases := []struct {
+		name        string
+		badSends    int
+		badReceives int
+		attempts    int
+		ok          bool
+	}{
+		{"clean", 0, 0, 1, true},
+		{"nak", 1, 0, 1, false},
+		{"nak_retry", 1, 0, 2, true},
+		{"framing", 0, 1, 1, false},
+		{"framing_retry", 0, 1, 2, true},
+		{"too_many", 3, 1, 3, false},
+		{"enough", 3, 1, 4, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conn := &flakyConn{Emulator: newTestEmulator(t), badSends: c.badSends, badReceives: c.badReceives}
+			cgm := &CGM{Connection: conn}
+			cgm.SetRetryPolicy(RetryPolicy{Attempts: c.attempts})
+			first, last := cgm.ReadPageRange(EGVData)
+			if !c.ok {
+				if cgm.Error() == nil {
+					t.Errorf("ReadPageRange succeeded with %d attempts, want error", c.attempts)
+				}
+				return
+			}
+			if cgm.Error() != nil {
+				t.Fatal(cgm.Error())
+			}
+			if first != 312 || last != 312 {
+				t.Errorf("ReadPageRange == (%d, %d), want (312, 312)", first, last)
+			}
+		})
+	}
+}
+
+func TestReplayRetry(t *testing.T) {
+	var session bytes.Buffer
+	conn := &flakyConn{Emulator: newTestEmulator(t), badReceives: 1}
+	cgm := &CGM{Connection: NewRecorder(conn, &session)}
+	cgm.SetRetryPolicy(RetryPolicy{Attempts: 2})
+	want := cgm.ReadRecords(SensorData, 469)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	r, err := NewReplayer(&session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cgm = &CGM{Connection: r}
+	cgm.SetRetryPolicy(RetryPolicy{Attempts: 2})
+	got := cgm.ReadRecords(SensorData, 469)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(got) != len(want) {
+		t.Errorf("replay returned %d records, want %d", len(got), len(want))
+	}
+}
+
+func TestNoRetryForInvalidCommand(t *testing.T) {
+	conn := &flakyConn{Emulator: newTestEmulator(t)}
+	cgm := &CGM{Connection: conn}
+	cgm.SetRetryPolicy(RetryPolicy{Attempts: 3})
+	cgm.Cmd(Command(0xFE))
+	if cgm.Error() == nil || IsTransient(cgm.Error()) {
+		t.Errorf("Cmd(FE) returned %v, want permanent error", cgm.Error())
+	}
+}
+
+func TestNoRetryAfterWrite(t *testing.T) {
+	cases := []struct {
+		name        string
+		badSends    int
+		badReceives int
+		ok          bool
+	}{
+		{"nak", 1, 0, true},
+		{"lost_response", 0, 1, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conn := &flakyConn{Emulator: newTestEmulator(t)}
+			cgm := &CGM{Connection: conn}
+			cgm.SetRetryPolicy(RetryPolicy{Attempts: 3})
+			before := len(cgm.ReadHistory(SoftwareData, time.Time{}))
+			conn.badSends, conn.badReceives = c.badSends, c.badReceives
+			err := cgm.SetSoftwareParametersE(`<SoftwareParameters Owner="Emulator" />`)
+			if c.ok && err != nil {
+				t.Fatal(err)
+			}
+			if !c.ok && !IsTransient(err) {
+				t.Errorf("SetSoftwareParametersE returned %v, want transient error", err)
+			}
+			// The write must be executed exactly once.
+			after := len(cgm.ReadHistory(SoftwareData, time.Time{}))
+			if cgm.Error() != nil {
+				t.Fatal(cgm.Error())
+			}
+			if after != before+1 {
+				t.Errorf("receiver has %d SoftwareData records, want %d", after, before+1)
+			}
+		})
+	}
+}
+
+// emulatorPort is a usbPort that passes packets to an Emulator
+// and returns its responses. The first few responses are truncated:
+// their last bytes arrive only after the given delay.
+type emulatorPort struct {
+	e         *Emulator
+	r         *io.PipeReader
+	w         *io.PipeWriter
+	truncated int
+	delay     time.Duration
+}
+
+func newEmulatorPort(e *Emulator, truncated int, delay time.Duration) *emulatorPort {
+	r, w := io.Pipe()
+	return &emulatorPort{e: e, r: r, w: w, truncated: truncated, delay: delay}
+}
+
+func (p *emulatorPort) Write(data []byte) error {
+	err := p.e.Send(data)
+	if err != nil {
+		return err
+	}
+	resp := make([]byte, 4)
+	err = p.e.Receive(resp)
+	if err != nil {
+		return err
+	}
+	rest := make([]byte, int(unmarshalUint16(resp[1:3]))-len(resp))
+	err = p.e.Receive(rest)
+	if err != nil {
+		return err
+	}
+	resp = append(resp, rest...)
+	if p.truncated > 0 {
+		p.truncated--
+		n := len(resp) - 3
+		tail := resp[n:]
+		resp = resp[:n]
+		go func() {
+			time.Sleep(p.delay)
+			_, _ = p.w.Write(tail)
+		}()
+	}
+	_, err = p.w.Write(resp)
+	return err
+}
+
+func (p *emulatorPort) Read(data []byte) error {
+	_, err := io.ReadFull(p.r, data)
+	return err
+}
+
+func (p *emulatorPort) Close() error {
+	_ = p.w.Close()
+	return p.r.Close()
+}
+
+func TestUSBRetryTruncated(t *testing.T) {
+	cases := []struct {
+		name     string
+		attempts int
+		ok       bool
+	}{
+		{"no_retry", 1, false},
+		{"retry", 2, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// The rest of the truncated packet arrives after
+			// the receive times out, so it must be drained.
+			port := newEmulatorPort(newTestEmulator(t), 1, 100*time.Millisecond)
+			cgm := &CGM{Connection: newUSBConn(port, 50*time.Millisecond)}
+			defer cgm.Close()
+			cgm.SetRetryPolicy(RetryPolicy{Attempts: c.attempts})
+			records := cgm.ReadRecords(SensorData, 469)
+			if !c.ok {
+				if !IsTransient(cgm.Error()) {
+					t.Errorf("ReadRecords of truncated packet returned %v, want transient error", cgm.Error())
+				}
+				return
+			}
+			if cgm.Error() != nil {
+				t.Fatal(cgm.Error())
+			}
+			checkRecords(t, records, testFileName(pageTestCase{SensorData, 469, 0})+".json")
+		})
+	}
+}
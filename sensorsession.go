@@ -0,0 +1,93 @@
+package dexcom
+
+import (
+	"time"
+)
+
+// WarmUpPeriod is the time after a sensor is started
+// before the receiver displays glucose values.
+const WarmUpPeriod = 2 * time.Hour
+
+// SensorSession represents the period during which a sensor was in use.
+// Times are display times.
+type SensorSession struct {
+	Start     time.Time
+	End       time.Time // zero if the session has not ended
+	WarmUpEnd time.Time
+	Records   Records // EGV, sensor, and calibration records, most recent first
+
+	// System times of the start and end, used to assign records.
+	startSys, endSys time.Time
+}
+
+// Active reports whether the session has not ended.
+func (s SensorSession) Active() bool {
+	return s.End.IsZero()
+}
+
+// Duration returns the length of the session, measured in system time,
+// or 0 if it has not ended.
+func (s SensorSession) Duration() time.Duration {
+	if s.Active() {
+		return 0
+	}
+	return s.endSys.Sub(s.startSys)
+}
+
+// Sessions pairs the Started and Stopped events in InsertionTimeData records
+// and returns the resulting sensor sessions, most recent first.
+// The records must be in reverse chronological order (as returned by MergeHistory),
+// and the EGV, sensor, and calibration records among them are assigned
+// to the sessions in which they fall.
+// Records are assigned by system time, which is unaffected
+// by changes to the receiver's display time.
+// A session that is followed by another Started event without a Stopped event
+// ends when the next one starts. Stopped events without a preceding Started event
+// (for example, when the start is older than the records) are ignored.
+func Sessions(records Records) []SensorSession {
+	var sessions []SensorSession
+	open := false
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		ins, ok := r.Info.(*InsertionInfo)
+		if !ok {
+			continue
+		}
+		t, sys := r.Time(), r.Timestamp.SystemTime
+		switch ins.Event {
+		case Started:
+			if open {
+				s := &sessions[len(sessions)-1]
+				s.End, s.endSys = t, sys
+			}
+			sessions = append(sessions, SensorSession{Start: t, WarmUpEnd: t.Add(WarmUpPeriod), startSys: sys})
+			open = true
+		case Stopped:
+			if open {
+				s := &sessions[len(sessions)-1]
+				s.End, s.endSys = t, sys
+				open = false
+			}
+		}
+	}
+	// Reverse the sessions to put the most recent first.
+	for i, j := 0, len(sessions)-1; i < j; i, j = i+1, j-1 {
+		sessions[i], sessions[j] = sessions[j], sessions[i]
+	}
+	for _, r := range records {
+		switch r.PageType() {
+		case EGVData, SensorData, CalibrationData:
+		default:
+			continue
+		}
+		t := r.Timestamp.SystemTime
+		for i := range sessions {
+			s := &sessions[i]
+			if !t.Before(s.startSys) && (s.Active() || t.Before(s.endSys)) {
+				s.Records = append(s.Records, r)
+				break
+			}
+		}
+	}
+	return sessions
+}
@@ -0,0 +1,99 @@
+package dexcom
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	t0 := parseTime("2017-09-01 08:00:00")
+	day := 24 * time.Hour
+	insertions := Records{
+		testRecord(t0.Add(8*day), &InsertionInfo{Event: Started}),
+		testRecord(t0.Add(5*day+time.Hour), &InsertionInfo{Event: Started}),
+		testRecord(t0.Add(5*day), &InsertionInfo{Event: Stopped}),
+		testRecord(t0, &InsertionInfo{Event: Started}),
+		testRecord(t0.Add(-time.Hour), &InsertionInfo{Event: Stopped}),
+	}
+	var egvs Records
+	for k := 20; k >= -1; k-- {
+		rt := t0.Add(time.Duration(k) * 12 * time.Hour)
+		egvs = append(egvs, testRecord(rt, &EGVInfo{Glucose: uint16(100 + k)}))
+	}
+	sessions := Sessions(MergeHistory(insertions, egvs))
+	cases := []struct {
+		start, end time.Time
+		active     bool
+		records    int
+	}{
+		{t0.Add(8 * day), time.Time{}, true, 5},
+		{t0.Add(5*day + time.Hour), t0.Add(8 * day), false, 5},
+		{t0, t0.Add(5 * day), false, 10},
+	}
+	if len(sessions) != len(cases) {
+		t.Fatalf("Sessions returned %d sessions, want %d", len(sessions), len(cases))
+	}
+	for i, c := range cases {
+		s := sessions[i]
+		if !s.Start.Equal(c.start) || !s.End.Equal(c.end) {
+			t.Errorf("session %d is from %v to %v, want %v to %v", i, s.Start, s.End, c.start, c.end)
+		}
+		if s.Active() != c.active {
+			t.Errorf("session %d Active() == %v, want %v", i, s.Active(), c.active)
+		}
+		if !s.WarmUpEnd.Equal(c.start.Add(WarmUpPeriod)) {
+			t.Errorf("session %d warm-up ends at %v, want %v", i, s.WarmUpEnd, c.start.Add(WarmUpPeriod))
+		}
+		want := time.Duration(0)
+		if !c.active {
+			want = c.end.Sub(c.start)
+		}
+		if s.Duration() != want {
+			t.Errorf("session %d Duration() == %v, want %v", i, s.Duration(), want)
+		}
+		if len(s.Records) != c.records {
+			t.Errorf("session %d has %d records, want %d", i, len(s.Records), c.records)
+			continue
+		}
+		for j, r := range s.Records {
+			if r.Time().Before(s.Start) || (!s.Active() && !r.Time().Before(s.End)) {
+				t.Errorf("session %d record %d at %v is outside the session", i, j, r.Time())
+			}
+			if j > 0 && r.Time().After(s.Records[j-1].Time()) {
+				t.Errorf("session %d records are not in reverse chronological order", i)
+			}
+		}
+	}
+}
+
+func TestSessionsDisplayTimeChange(t *testing.T) {
+	t0 := parseTime("2017-09-01 08:00:00")
+	// The display time is set back 3 hours an hour after the sensor starts.
+	offset := -3 * time.Hour
+	record := func(sys time.Time, display time.Time, r Record) Record {
+		r.Timestamp = Timestamp{SystemTime: sys, DisplayTime: display}
+		return r
+	}
+	egv := Record{Info: &EGVInfo{Glucose: 100}}
+	records := Records{
+		record(t0.Add(6*time.Hour), t0.Add(6*time.Hour+offset), egv),
+		record(t0.Add(5*time.Hour), t0.Add(5*time.Hour+offset), Record{Info: &InsertionInfo{Event: Stopped}}),
+		record(t0.Add(2*time.Hour), t0.Add(2*time.Hour+offset), egv),
+		record(t0, t0, Record{Info: &InsertionInfo{Event: Started}}),
+		record(t0.Add(-30*time.Minute), t0.Add(-30*time.Minute), egv),
+	}
+	sessions := Sessions(records)
+	if len(sessions) != 1 {
+		t.Fatalf("Sessions returned %d sessions, want 1", len(sessions))
+	}
+	s := sessions[0]
+	if !s.Start.Equal(t0) || !s.End.Equal(t0.Add(5*time.Hour+offset)) {
+		t.Errorf("session is from %v to %v, want %v to %v", s.Start, s.End, t0, t0.Add(5*time.Hour+offset))
+	}
+	if s.Duration() != 5*time.Hour {
+		t.Errorf("session Duration() == %v, want %v", s.Duration(), 5*time.Hour)
+	}
+	if len(s.Records) != 1 || !s.Records[0].Timestamp.SystemTime.Equal(t0.Add(2*time.Hour)) {
+		t.Errorf("session records == %v, want the record at system time %v", s.Records, t0.Add(2*time.Hour))
+	}
+}
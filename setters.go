@@ -0,0 +1,268 @@
+package dexcom
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// VerifyError indicates that the value read back from the receiver
+// after a write command does not match the value that was written.
+type VerifyError struct {
+	Command Command
+	Wrote   interface{}
+	Read    interface{}
+}
+
+func (e VerifyError) Error() string {
+	return fmt.Sprintf("%v: wrote %v but read back %v", e.Command, e.Wrote, e.Read)
+}
+
+const (
+	transmitterIDLength = 5
+
+	// Maximum difference allowed when verifying a new system time.
+	systemTimeTolerance = 2 * time.Second
+
+	// Maximum length of the XML in a software parameters record,
+	// which also contains a timestamp and a CRC.
+	maxSoftwareParameters = pageDataSize - 8 - 2
+)
+
+func (lang Language) valid() bool {
+	switch lang {
+	case German, English, Spanish, French, Italian, Dutch, Swedish:
+		return true
+	default:
+		return false
+	}
+}
+
+func (units GlucoseUnits) valid() bool {
+	return units == MgPerDL || units == MmolPerL
+}
+
+func (mode ClockMode) valid() bool {
+	return mode == Clock24Hour || mode == Clock12Hour
+}
+
+func (c ChargerCurrent) valid() bool {
+	return c <= ChargerSuspended
+}
+
+func validTransmitterID(id string) bool {
+	_, err := encodeTransmitterID(id)
+	return err == nil
+}
+
+// writeSetting performs a write command and then a read command,
+// and returns a VerifyError if the value read back differs from the one written.
+func (cgm *CGM) writeSetting(write Command, params []byte, read Command, wrote interface{}, decode func([]byte) interface{}) error {
+	_, err := cgm.CmdE(write, params...)
+	if err != nil {
+		return err
+	}
+	v, err := cgm.readSetting(read, len(params))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(v, params) {
+		return VerifyError{Command: write, Wrote: wrote, Read: decode(v)}
+	}
+	return nil
+}
+
+// SetTransmitterID sets the ID of the transmitter paired with the receiver.
+// The ID must consist of 5 digits or upper-case letters
+// other than I, O, V, and Z.
+func (cgm *CGM) SetTransmitterID(id string) {
+	if cgm.Error() != nil {
+		return
+	}
+	cgm.SetError(cgm.SetTransmitterIDE(id))
+}
+
+// SetTransmitterIDE is like SetTransmitterID, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) SetTransmitterIDE(id string) error {
+	if !validTransmitterID(id) {
+		return fmt.Errorf("invalid transmitter ID %q", id)
+	}
+	return cgm.writeSetting(WriteTransmitterID, []byte(id), ReadTransmitterID, id, func(v []byte) interface{} {
+		return string(v)
+	})
+}
+
+// SetLanguage sets the receiver's display language.
+func (cgm *CGM) SetLanguage(lang Language) {
+	if cgm.Error() != nil {
+		return
+	}
+	cgm.SetError(cgm.SetLanguageE(lang))
+}
+
+// SetLanguageE is like SetLanguage, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) SetLanguageE(lang Language) error {
+	if !lang.valid() {
+		return fmt.Errorf("invalid language %v", lang)
+	}
+	return cgm.writeSetting(WriteLanguage, marshalUint16(uint16(lang)), ReadLanguage, lang, func(v []byte) interface{} {
+		return Language(unmarshalUint16(v))
+	})
+}
+
+// SetGlucoseUnits sets the units in which the receiver displays glucose values.
+func (cgm *CGM) SetGlucoseUnits(units GlucoseUnits) {
+	if cgm.Error() != nil {
+		return
+	}
+	cgm.SetError(cgm.SetGlucoseUnitsE(units))
+}
+
+// SetGlucoseUnitsE is like SetGlucoseUnits, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) SetGlucoseUnitsE(units GlucoseUnits) error {
+	if !units.valid() {
+		return fmt.Errorf("invalid glucose units %v", units)
+	}
+	return cgm.writeSetting(WriteGlucoseUnits, []byte{byte(units)}, ReadGlucoseUnits, units, func(v []byte) interface{} {
+		return GlucoseUnits(v[0])
+	})
+}
+
+// SetBlindMode turns the receiver's blinded mode on or off.
+func (cgm *CGM) SetBlindMode(blind bool) {
+	if cgm.Error() != nil {
+		return
+	}
+	cgm.SetError(cgm.SetBlindModeE(blind))
+}
+
+// SetBlindModeE is like SetBlindMode, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) SetBlindModeE(blind bool) error {
+	b := byte(0)
+	if blind {
+		b = 1
+	}
+	return cgm.writeSetting(WriteBlindMode, []byte{b}, ReadBlindMode, blind, func(v []byte) interface{} {
+		return v[0] != 0
+	})
+}
+
+// SetClockMode sets the receiver's time display format.
+func (cgm *CGM) SetClockMode(mode ClockMode) {
+	if cgm.Error() != nil {
+		return
+	}
+	cgm.SetError(cgm.SetClockModeE(mode))
+}
+
+// SetClockModeE is like SetClockMode, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) SetClockModeE(mode ClockMode) error {
+	if !mode.valid() {
+		return fmt.Errorf("invalid clock mode %v", mode)
+	}
+	return cgm.writeSetting(WriteClockMode, []byte{byte(mode)}, ReadClockMode, mode, func(v []byte) interface{} {
+		return ClockMode(v[0])
+	})
+}
+
+// SetChargerCurrentSetting sets the receiver's charger current setting.
+func (cgm *CGM) SetChargerCurrentSetting(c ChargerCurrent) {
+	if cgm.Error() != nil {
+		return
+	}
+	cgm.SetError(cgm.SetChargerCurrentSettingE(c))
+}
+
+// SetChargerCurrentSettingE is like SetChargerCurrentSetting, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) SetChargerCurrentSettingE(c ChargerCurrent) error {
+	if !c.valid() {
+		return fmt.Errorf("invalid charger current setting %v", c)
+	}
+	return cgm.writeSetting(WriteChargerCurrentSetting, []byte{byte(c)}, ReadChargerCurrentSetting, c, func(v []byte) interface{} {
+		return ChargerCurrent(v[0])
+	})
+}
+
+// SetSystemTime sets the Dexcom receiver's system time.
+// Note that this changes the system timestamps of subsequent records;
+// to change the time displayed by the receiver, use SetDisplayTime.
+func (cgm *CGM) SetSystemTime(t time.Time) {
+	if cgm.Error() != nil {
+		return
+	}
+	cgm.SetError(cgm.SetSystemTimeE(t))
+}
+
+// SetSystemTimeE is like SetSystemTime, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) SetSystemTimeE(t time.Time) error {
+	if fromTime(t) < 0 || fromTime(t) > math.MaxUint32 {
+		return fmt.Errorf("invalid system time %s", t.Format(UserTimeLayout))
+	}
+	_, err := cgm.CmdE(WriteSystemTime, marshalUint32(uint32(fromTime(t)))...)
+	if err != nil {
+		return err
+	}
+	sys, err := cgm.ReadSystemTimeE()
+	if err != nil {
+		return err
+	}
+	delta := sys.Sub(t)
+	if delta < -systemTimeTolerance || delta > systemTimeTolerance {
+		return VerifyError{Command: WriteSystemTime, Wrote: t.Format(UserTimeLayout), Read: sys.Format(UserTimeLayout)}
+	}
+	return nil
+}
+
+// SetSoftwareParameters writes an XML element containing software parameters,
+// which the receiver stores as a new SoftwareData record.
+func (cgm *CGM) SetSoftwareParameters(xml string) {
+	if cgm.Error() != nil {
+		return
+	}
+	cgm.SetError(cgm.SetSoftwareParametersE(xml))
+}
+
+// SetSoftwareParametersE is like SetSoftwareParameters, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) SetSoftwareParametersE(xml string) error {
+	if len(xml) > maxSoftwareParameters {
+		return fmt.Errorf("software parameters too long (%d bytes)", len(xml))
+	}
+	params := umarshalXMLBytes([]byte(xml))
+	if _, invalid := params["InvalidXML"]; invalid {
+		return fmt.Errorf("invalid software parameters %q", xml)
+	}
+	_, err := cgm.CmdE(WriteSoftwareParameters, []byte(xml)...)
+	if err != nil {
+		return err
+	}
+	r, err := cgm.readLatestRecord(SoftwareData)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(r.XML(), params) {
+		return VerifyError{Command: WriteSoftwareParameters, Wrote: params, Read: r.XML()}
+	}
+	return nil
+}
+
+// readLatestRecord returns the most recent record of the given type.
+func (cgm *CGM) readLatestRecord(pageType PageType) (Record, error) {
+	records, err := cgm.ReadCountE(pageType, 1)
+	if err != nil {
+		return Record{}, err
+	}
+	if len(records) == 0 {
+		return Record{}, fmt.Errorf("no %v records", pageType)
+	}
+	return records[0], nil
+}
@@ -0,0 +1,109 @@
+package dexcom
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetters(t *testing.T) {
+	cgm := &CGM{Connection: NewEmulator()}
+	check := func(name string, err error, got, want interface{}) {
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			return
+		}
+		if got != want {
+			t.Errorf("%s: read back %v, want %v", name, got, want)
+		}
+	}
+	check("SetTransmitterID", cgm.SetTransmitterIDE("6ABCD"), cgm.ReadTransmitterID(), "6ABCD")
+	check("SetLanguage", cgm.SetLanguageE(Swedish), cgm.ReadLanguage(), Swedish)
+	check("SetGlucoseUnits", cgm.SetGlucoseUnitsE(MmolPerL), cgm.ReadGlucoseUnits(), MmolPerL)
+	check("SetBlindMode", cgm.SetBlindModeE(true), cgm.ReadBlindMode(), true)
+	check("SetClockMode", cgm.SetClockModeE(Clock12Hour), cgm.ReadClockMode(), Clock12Hour)
+	check("SetChargerCurrentSetting", cgm.SetChargerCurrentSettingE(ChargerMax), cgm.ReadChargerCurrentSetting(), ChargerMax)
+	sys := parseTime("2017-09-17 11:13:17")
+	check("SetSystemTime", cgm.SetSystemTimeE(sys), cgm.ReadSystemTime(), sys)
+	params := `<SoftwareParameters Owner="Emulator" />`
+	check("SetSoftwareParameters", cgm.SetSoftwareParametersE(params), cgm.ReadXMLRecord(SoftwareData).XML()["Owner"], "Emulator")
+	if cgm.Error() != nil {
+		t.Error(cgm.Error())
+	}
+}
+
+func TestSetterValidation(t *testing.T) {
+	cases := []struct {
+		name string
+		set  func(*CGM) error
+	}{
+		{"short transmitter ID", func(cgm *CGM) error { return cgm.SetTransmitterIDE("6ABC") }},
+		{"lower-case transmitter ID", func(cgm *CGM) error { return cgm.SetTransmitterIDE("6abcd") }},
+		{"transmitter ID with O", func(cgm *CGM) error { return cgm.SetTransmitterIDE("6ABOD") }},
+		{"transmitter ID with V", func(cgm *CGM) error { return cgm.SetTransmitterIDE("6ABVD") }},
+		{"unknown language", func(cgm *CGM) error { return cgm.SetLanguageE(Language(1049)) }},
+		{"unknown glucose units", func(cgm *CGM) error { return cgm.SetGlucoseUnitsE(GlucoseUnits(0)) }},
+		{"unknown clock mode", func(cgm *CGM) error { return cgm.SetClockModeE(ClockMode(2)) }},
+		{"unknown charger current", func(cgm *CGM) error { return cgm.SetChargerCurrentSettingE(ChargerCurrent(5)) }},
+		{"system time before epoch", func(cgm *CGM) error { return cgm.SetSystemTimeE(parseTime("2008-12-31 23:59:59")) }},
+		{"invalid software parameters", func(cgm *CGM) error { return cgm.SetSoftwareParametersE("<Software") }},
+		{"long software parameters", func(cgm *CGM) error {
+			return cgm.SetSoftwareParametersE(`<P X="` + strings.Repeat("x", maxSoftwareParameters) + `" />`)
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := NewEmulator()
+			// Any command sent after validation fails is an error.
+			e.Close()
+			cgm := &CGM{Connection: e}
+			err := c.set(cgm)
+			if err == nil {
+				t.Fatalf("setter succeeded, want error")
+			}
+			if strings.Contains(err.Error(), "emulator") {
+				t.Errorf("setter sent a command before validating its argument: %v", err)
+			}
+		})
+	}
+}
+
+// ignoreWrites is a Connection that acknowledges write commands
+// without changing the underlying emulator's settings.
+type ignoreWrites struct {
+	*Emulator
+}
+
+func (c ignoreWrites) Send(data []byte) error {
+	cmd := Command(data[3])
+	if _, found := emulatorWrites[cmd]; found || cmd == WriteSystemTime {
+		data = marshalPacket(Ping, nil)
+	}
+	return c.Emulator.Send(data)
+}
+
+func TestSetterVerifyError(t *testing.T) {
+	cgm := &CGM{Connection: ignoreWrites{NewEmulator()}}
+	cgm.SetTransmitterID("6ABCD")
+	err := cgm.Error()
+	v, ok := err.(VerifyError)
+	if !ok {
+		t.Fatalf("SetTransmitterID error = %v, want VerifyError", err)
+	}
+	if v.Command != WriteTransmitterID || v.Wrote != "6ABCD" || v.Read != "00000" {
+		t.Errorf("SetTransmitterID error = %+v", v)
+	}
+	// The sticky error prevents further commands.
+	cgm.SetLanguage(German)
+	if cgm.Error() != err {
+		t.Errorf("sticky error changed to %v", cgm.Error())
+	}
+}
+
+func TestSetSystemTimeVerifyError(t *testing.T) {
+	cgm := &CGM{Connection: ignoreWrites{NewEmulator()}}
+	err := cgm.SetSystemTimeE(time.Now().Add(-time.Hour))
+	if _, ok := err.(VerifyError); !ok {
+		t.Errorf("SetSystemTimeE error = %v, want VerifyError", err)
+	}
+}
@@ -0,0 +1,388 @@
+package dexcom
+
+import (
+	"fmt"
+	"time"
+)
+
+// Language represents the receiver's display language,
+// as a Windows locale identifier.
+type Language uint16
+
+//go:generate stringer -type Language
+
+// Receiver languages.
+const (
+	German  Language = 1031
+	English Language = 1033
+	Spanish Language = 1034
+	French  Language = 1036
+	Italian Language = 1040
+	Dutch   Language = 1043
+	Swedish Language = 1053
+)
+
+// GlucoseUnits represents the units in which the receiver displays glucose values.
+type GlucoseUnits byte
+
+//go:generate stringer -type GlucoseUnits
+
+// Glucose units.
+const (
+	MgPerDL  GlucoseUnits = 1
+	MmolPerL GlucoseUnits = 2
+)
+
+// ClockMode represents the receiver's time display format.
+type ClockMode byte
+
+//go:generate stringer -type ClockMode
+
+// Clock modes.
+const (
+	Clock24Hour ClockMode = 0
+	Clock12Hour ClockMode = 1
+)
+
+// BatteryState represents the state of the receiver's battery.
+type BatteryState byte
+
+//go:generate stringer -type BatteryState
+
+// Battery states.
+const (
+	Charging    BatteryState = 1
+	NotCharging BatteryState = 2
+	NTCFault    BatteryState = 3
+	BadBattery  BatteryState = 4
+)
+
+// ChargerCurrent represents the receiver's charger current setting.
+type ChargerCurrent byte
+
+//go:generate stringer -type ChargerCurrent
+
+// Charger current settings.
+const (
+	ChargerOff       ChargerCurrent = 0
+	Charger100mA     ChargerCurrent = 1
+	Charger500mA     ChargerCurrent = 2
+	ChargerMax       ChargerCurrent = 3
+	ChargerSuspended ChargerCurrent = 4
+)
+
+// readSetting performs a read command and checks
+// that the response contains at least n bytes.
+func (cgm *CGM) readSetting(cmd Command, n int) ([]byte, error) {
+	v, err := cgm.CmdE(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(v) < n {
+		return nil, fmt.Errorf("%v: unexpected response length (%d)", cmd, len(v))
+	}
+	return v, nil
+}
+
+// ReadTransmitterID returns the ID of the transmitter paired with the receiver.
+func (cgm *CGM) ReadTransmitterID() string {
+	if cgm.Error() != nil {
+		return ""
+	}
+	id, err := cgm.ReadTransmitterIDE()
+	cgm.SetError(err)
+	return id
+}
+
+// ReadTransmitterIDE is like ReadTransmitterID, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadTransmitterIDE() (string, error) {
+	v, err := cgm.readSetting(ReadTransmitterID, 0)
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
+
+// ReadLanguage returns the receiver's display language.
+func (cgm *CGM) ReadLanguage() Language {
+	if cgm.Error() != nil {
+		return 0
+	}
+	lang, err := cgm.ReadLanguageE()
+	cgm.SetError(err)
+	return lang
+}
+
+// ReadLanguageE is like ReadLanguage, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadLanguageE() (Language, error) {
+	v, err := cgm.readSetting(ReadLanguage, 2)
+	if err != nil {
+		return 0, err
+	}
+	return Language(unmarshalUint16(v)), nil
+}
+
+// ReadRTC returns the value of the receiver's real-time clock.
+func (cgm *CGM) ReadRTC() time.Time {
+	if cgm.Error() != nil {
+		return time.Time{}
+	}
+	t, err := cgm.ReadRTCE()
+	cgm.SetError(err)
+	return t
+}
+
+// ReadRTCE is like ReadRTC, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadRTCE() (time.Time, error) {
+	v, err := cgm.readSetting(ReadRTC, 4)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return unmarshalTime(v), nil
+}
+
+// ReadSystemTimeOffset returns the offset of the receiver's
+// system time from its real-time clock.
+func (cgm *CGM) ReadSystemTimeOffset() time.Duration {
+	if cgm.Error() != nil {
+		return 0
+	}
+	d, err := cgm.ReadSystemTimeOffsetE()
+	cgm.SetError(err)
+	return d
+}
+
+// ReadSystemTimeOffsetE is like ReadSystemTimeOffset, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadSystemTimeOffsetE() (time.Duration, error) {
+	v, err := cgm.readSetting(ReadSystemTimeOffset, 4)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(unmarshalInt32(v)) * time.Second, nil
+}
+
+// ReadBatteryLevel returns the receiver's battery level as a percentage.
+func (cgm *CGM) ReadBatteryLevel() int {
+	if cgm.Error() != nil {
+		return 0
+	}
+	n, err := cgm.ReadBatteryLevelE()
+	cgm.SetError(err)
+	return n
+}
+
+// ReadBatteryLevelE is like ReadBatteryLevel, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadBatteryLevelE() (int, error) {
+	v, err := cgm.readSetting(ReadBatteryLevel, 4)
+	if err != nil {
+		return 0, err
+	}
+	return int(unmarshalUint32(v)), nil
+}
+
+// ReadBatteryState returns the state of the receiver's battery.
+func (cgm *CGM) ReadBatteryState() BatteryState {
+	if cgm.Error() != nil {
+		return 0
+	}
+	state, err := cgm.ReadBatteryStateE()
+	cgm.SetError(err)
+	return state
+}
+
+// ReadBatteryStateE is like ReadBatteryState, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadBatteryStateE() (BatteryState, error) {
+	v, err := cgm.readSetting(ReadBatteryState, 1)
+	if err != nil {
+		return 0, err
+	}
+	return BatteryState(v[0]), nil
+}
+
+// ReadGlucoseUnits returns the units in which the receiver displays glucose values.
+func (cgm *CGM) ReadGlucoseUnits() GlucoseUnits {
+	if cgm.Error() != nil {
+		return 0
+	}
+	units, err := cgm.ReadGlucoseUnitsE()
+	cgm.SetError(err)
+	return units
+}
+
+// ReadGlucoseUnitsE is like ReadGlucoseUnits, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadGlucoseUnitsE() (GlucoseUnits, error) {
+	v, err := cgm.readSetting(ReadGlucoseUnits, 1)
+	if err != nil {
+		return 0, err
+	}
+	return GlucoseUnits(v[0]), nil
+}
+
+// ReadBlindMode returns whether the receiver is in blinded mode,
+// in which glucose values are not displayed.
+func (cgm *CGM) ReadBlindMode() bool {
+	if cgm.Error() != nil {
+		return false
+	}
+	blind, err := cgm.ReadBlindModeE()
+	cgm.SetError(err)
+	return blind
+}
+
+// ReadBlindModeE is like ReadBlindMode, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadBlindModeE() (bool, error) {
+	v, err := cgm.readSetting(ReadBlindMode, 1)
+	if err != nil {
+		return false, err
+	}
+	return v[0] != 0, nil
+}
+
+// ReadClockMode returns the receiver's time display format.
+func (cgm *CGM) ReadClockMode() ClockMode {
+	if cgm.Error() != nil {
+		return 0
+	}
+	mode, err := cgm.ReadClockModeE()
+	cgm.SetError(err)
+	return mode
+}
+
+// ReadClockModeE is like ReadClockMode, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadClockModeE() (ClockMode, error) {
+	v, err := cgm.readSetting(ReadClockMode, 1)
+	if err != nil {
+		return 0, err
+	}
+	return ClockMode(v[0]), nil
+}
+
+// ReadDeviceMode returns the receiver's device mode.
+func (cgm *CGM) ReadDeviceMode() byte {
+	if cgm.Error() != nil {
+		return 0
+	}
+	mode, err := cgm.ReadDeviceModeE()
+	cgm.SetError(err)
+	return mode
+}
+
+// ReadDeviceModeE is like ReadDeviceMode, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadDeviceModeE() (byte, error) {
+	v, err := cgm.readSetting(ReadDeviceMode, 1)
+	if err != nil {
+		return 0, err
+	}
+	return v[0], nil
+}
+
+// ReadHardwareID returns the receiver's hardware board ID.
+func (cgm *CGM) ReadHardwareID() int {
+	if cgm.Error() != nil {
+		return 0
+	}
+	id, err := cgm.ReadHardwareIDE()
+	cgm.SetError(err)
+	return id
+}
+
+// ReadHardwareIDE is like ReadHardwareID, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadHardwareIDE() (int, error) {
+	v, err := cgm.readSetting(ReadHardwareID, 1)
+	if err != nil {
+		return 0, err
+	}
+	return int(v[0]), nil
+}
+
+// ReadFirmwareSettings gets the firmware settings from the Dexcom CGM receiver
+// and returns them as XMLInfo.
+func (cgm *CGM) ReadFirmwareSettings() XMLInfo {
+	if cgm.Error() != nil {
+		return nil
+	}
+	x, err := cgm.ReadFirmwareSettingsE()
+	cgm.SetError(err)
+	return x
+}
+
+// ReadFirmwareSettingsE is like ReadFirmwareSettings, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadFirmwareSettingsE() (XMLInfo, error) {
+	v, err := cgm.readSetting(ReadFirmwareSettings, 0)
+	if err != nil {
+		return nil, err
+	}
+	return umarshalXMLBytes(v), nil
+}
+
+// ReadChargerCurrentSetting returns the receiver's charger current setting.
+func (cgm *CGM) ReadChargerCurrentSetting() ChargerCurrent {
+	if cgm.Error() != nil {
+		return 0
+	}
+	c, err := cgm.ReadChargerCurrentSettingE()
+	cgm.SetError(err)
+	return c
+}
+
+// ReadChargerCurrentSettingE is like ReadChargerCurrentSetting, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadChargerCurrentSettingE() (ChargerCurrent, error) {
+	v, err := cgm.readSetting(ReadChargerCurrentSetting, 1)
+	if err != nil {
+		return 0, err
+	}
+	return ChargerCurrent(v[0]), nil
+}
+
+// ReadSetupWizardEnabled returns whether the receiver's setup wizard is enabled.
+func (cgm *CGM) ReadSetupWizardEnabled() bool {
+	if cgm.Error() != nil {
+		return false
+	}
+	enabled, err := cgm.ReadSetupWizardEnabledE()
+	cgm.SetError(err)
+	return enabled
+}
+
+// ReadSetupWizardEnabledE is like ReadSetupWizardEnabled, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadSetupWizardEnabledE() (bool, error) {
+	v, err := cgm.readSetting(ReadEnableSetupWizardFlag, 1)
+	if err != nil {
+		return false, err
+	}
+	return v[0] != 0, nil
+}
+
+// ReadSetupWizardState returns the state of the receiver's setup wizard.
+func (cgm *CGM) ReadSetupWizardState() byte {
+	if cgm.Error() != nil {
+		return 0
+	}
+	state, err := cgm.ReadSetupWizardStateE()
+	cgm.SetError(err)
+	return state
+}
+
+// ReadSetupWizardStateE is like ReadSetupWizardState, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadSetupWizardStateE() (byte, error) {
+	v, err := cgm.readSetting(ReadSetupWizardState, 1)
+	if err != nil {
+		return 0, err
+	}
+	return v[0], nil
+}
@@ -0,0 +1,81 @@
+package dexcom
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestReadSettings(t *testing.T) {
+	e := newTestEmulator(t)
+	rtc := parseTime("2017-09-17 11:13:17")
+	e.Set(ReadTransmitterID, []byte("6ABCD"))
+	e.Set(ReadLanguage, marshalUint16(uint16(French)))
+	e.Set(ReadRTC, marshalUint32(uint32(fromTime(rtc))))
+	e.Set(ReadSystemTimeOffset, marshalInt32(-3600))
+	e.Set(ReadBatteryLevel, marshalUint32(87))
+	e.Set(ReadBatteryState, []byte{byte(Charging)})
+	e.Set(ReadGlucoseUnits, []byte{byte(MmolPerL)})
+	e.Set(ReadBlindMode, []byte{1})
+	e.Set(ReadClockMode, []byte{byte(Clock12Hour)})
+	e.Set(ReadHardwareID, []byte{3})
+	e.Set(ReadChargerCurrentSetting, []byte{byte(Charger500mA)})
+	e.Set(ReadEnableSetupWizardFlag, []byte{1})
+	cgm := &CGM{Connection: e}
+	check := func(name string, got, want interface{}) {
+		if cgm.Error() != nil {
+			t.Errorf("%s: %v", name, cgm.Error())
+			cgm.SetError(nil)
+			return
+		}
+		if got != want {
+			t.Errorf("%s() == %v, want %v", name, got, want)
+		}
+	}
+	check("ReadTransmitterID", cgm.ReadTransmitterID(), "6ABCD")
+	check("ReadLanguage", cgm.ReadLanguage(), French)
+	check("ReadRTC", cgm.ReadRTC(), rtc)
+	check("ReadSystemTimeOffset", cgm.ReadSystemTimeOffset(), -time.Hour)
+	check("ReadBatteryLevel", cgm.ReadBatteryLevel(), 87)
+	check("ReadBatteryState", cgm.ReadBatteryState(), Charging)
+	check("ReadGlucoseUnits", cgm.ReadGlucoseUnits(), MmolPerL)
+	check("ReadBlindMode", cgm.ReadBlindMode(), true)
+	check("ReadClockMode", cgm.ReadClockMode(), Clock12Hour)
+	check("ReadDeviceMode", cgm.ReadDeviceMode(), byte(0))
+	check("ReadHardwareID", cgm.ReadHardwareID(), 3)
+	check("ReadChargerCurrentSetting", cgm.ReadChargerCurrentSetting(), Charger500mA)
+	check("ReadSetupWizardEnabled", cgm.ReadSetupWizardEnabled(), true)
+	check("ReadSetupWizardState", cgm.ReadSetupWizardState(), byte(0))
+	check("ReadFirmwareSettings", cgm.ReadFirmwareSettings()["FirmwareImageId"], "Emulator")
+}
+
+func TestReadSettingShortResponse(t *testing.T) {
+	e := newTestEmulator(t)
+	e.Set(ReadBatteryLevel, []byte{87})
+	cgm := &CGM{Connection: e}
+	_, err := cgm.ReadBatteryLevelE()
+	if err == nil {
+		t.Errorf("ReadBatteryLevelE succeeded with 1-byte response, want error")
+	}
+}
+
+func TestSettingStrings(t *testing.T) {
+	cases := []struct {
+		v fmt.Stringer
+		s string
+	}{
+		{English, "English"},
+		{Language(0), "Language(0)"},
+		{MgPerDL, "MgPerDL"},
+		{Clock24Hour, "Clock24Hour"},
+		{NTCFault, "NTCFault"},
+		{ChargerSuspended, "ChargerSuspended"},
+	}
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			if c.v.String() != c.s {
+				t.Errorf("String() == %q, want %q", c.v.String(), c.s)
+			}
+		})
+	}
+}
@@ -13,7 +13,7 @@ import (
 type bleConn struct {
 	*ble.Connection
 	tx ble.Characteristic
-	rx chan byte
+	rx *byteQueue
 }
 
 const (
@@ -53,16 +53,20 @@ func (conn *bleConn) Send(data []byte) error {
 
 // Receive reads data from the BLE connection.
 func (conn *bleConn) Receive(data []byte) error {
-	const receiveTimeout = 5 * time.Second
-	for i := 0; i < len(data); i++ {
-		select {
-		case b := <-conn.rx:
-			data[i] = b
-		case <-time.After(receiveTimeout):
-			return fmt.Errorf("BLE receive timeout")
-		}
-	}
-	return nil
+	return conn.rx.receive(data)
+}
+
+// Drain discards any data waiting to be received over the BLE connection,
+// including the rest of a response that is still arriving.
+func (conn *bleConn) Drain() {
+	conn.rx.drain(drainTimeout)
+}
+
+// SetDeadline sets the time after which Receive fails.
+// A zero time means no deadline.
+// Sending is not affected.
+func (conn *bleConn) SetDeadline(t time.Time) error {
+	return conn.rx.SetDeadline(t)
 }
 
 func connect(conn *ble.Connection) error {
@@ -173,10 +177,10 @@ func OpenBLE() (Connection, error) {
 		conn.Close()
 		return nil, err
 	}
-	rx := make(chan byte, 1600)
+	rx := newByteQueue(receiveTimeout)
 	err = conn.HandleNotify(receiveData, func(data []byte) {
 		for _, b := range data {
-			rx <- b
+			rx.c <- b
 		}
 	})
 	if err != nil {
@@ -0,0 +1,362 @@
+package dexcom
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// Source is a source of receiver records.
+// It is implemented by CGM, which reads from a live receiver,
+// and by PageStore, which reads from pages saved on disk.
+type Source interface {
+	ReadPageRange(PageType) (int, int)
+	ReadPage(PageType, int) []byte
+	ReadRecords(PageType, int) Records
+	IterRecords(PageType, int, int, RecordFunc)
+	ReadHistory(PageType, time.Time) Records
+	ReadRange(PageType, time.Time, time.Time) Records
+	ReadCount(PageType, int) Records
+	ReadXMLRecord(PageType) Record
+	ReadDisplayTime() time.Time
+	SetLenient(CRCErrorFunc)
+	Error() error
+	SetError(error)
+}
+
+// OpenSource returns a PageStore for the given files if there are any,
+// or else opens a connection to the receiver.
+func OpenSource(files ...string) Source {
+	if len(files) == 0 {
+		return Open()
+	}
+	s, err := LoadPages(files...)
+	if err != nil {
+		s = NewPageStore()
+		s.SetError(err)
+	}
+	return s
+}
+
+// PageStore is a Source backed by database pages
+// that were previously read from a receiver.
+type PageStore struct {
+	pages       map[PageType]map[int][]byte
+	info        *PartitionInfo
+	displayTime time.Time
+	skipped     CRCErrorFunc
+	err         error
+}
+
+// NewPageStore returns an empty PageStore.
+func NewPageStore() *PageStore {
+	return &PageStore{pages: make(map[PageType]map[int][]byte)}
+}
+
+// LoadPages returns a PageStore containing the pages in the given files.
+// Each file can be an archive written by g4backup,
+// a session log written by a Recorder,
+// or a single page in hexadecimal as printed by rawpage.
+func LoadPages(files ...string) (*PageStore, error) {
+	s := NewPageStore()
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		err = s.load(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *PageStore) load(data []byte) error {
+	text := bytes.TrimSpace(data)
+	if len(text) == 0 {
+		return fmt.Errorf("no pages found")
+	}
+	switch text[0] {
+	case '{':
+		a, err := ReadArchive(bytes.NewReader(text))
+		if err != nil {
+			return err
+		}
+		return s.AddArchive(a)
+	case sendPrefix, receivePrefix, commentPrefix:
+		r, err := NewReplayer(bytes.NewReader(text))
+		if err != nil {
+			return err
+		}
+		return s.addSession(r.events)
+	default:
+		v, err := hex.DecodeString(strings.Join(strings.Fields(string(text)), ""))
+		if err != nil {
+			return err
+		}
+		return s.AddPage(v)
+	}
+}
+
+// AddArchive adds the pages in an archive to the store.
+func (s *PageStore) AddArchive(a *Archive) error {
+	for _, p := range a.Pages {
+		err := s.AddPage(p.Data)
+		if err != nil {
+			return fmt.Errorf("%v page %d: %v", p.Type, p.Number, err)
+		}
+	}
+	if a.PartitionInfo != nil {
+		s.info = a.PartitionInfo
+	}
+	if a.DisplayTime.After(s.displayTime) {
+		s.displayTime = a.DisplayTime
+	}
+	return nil
+}
+
+// addSession adds the pages in the responses to ReadDatabasePages commands
+// in a recorded session, and the partition info if it was read.
+func (s *PageStore) addSession(events []sessionEvent) error {
+	for i, e := range events {
+		if !e.send || len(e.data) < minPacket {
+			continue
+		}
+		cmd := Command(e.data[3])
+		if cmd != ReadDatabasePages && cmd != ReadDatabasePartitionInfo {
+			continue
+		}
+		var resp []byte
+		for _, r := range events[i+1:] {
+			if r.send {
+				break
+			}
+			resp = append(resp, r.data...)
+		}
+		if len(resp) < minPacket {
+			continue
+		}
+		if resp[0] != startOfMessage {
+			return fmt.Errorf("%v: unexpected message header % X in session", cmd, resp[:4])
+		}
+		n := int(unmarshalUint16(resp[1:3]))
+		if n < minPacket || n > maxPacket {
+			return fmt.Errorf("%v: invalid packet length %d in session", cmd, n)
+		}
+		if n > len(resp) {
+			continue
+		}
+		crc := unmarshalUint16(resp[n-2 : n])
+		calc := crc16(resp[:n-2])
+		if crc != calc {
+			return CRCError{
+				Kind:     "packet",
+				Received: crc,
+				Computed: calc,
+				PageType: InvalidPage,
+				Data:     resp[:n-2],
+			}
+		}
+		if Command(resp[3]) != Ack {
+			continue
+		}
+		v := resp[4 : n-2]
+		if cmd == ReadDatabasePartitionInfo {
+			info, err := UnmarshalPartitionInfo(v)
+			if err != nil {
+				return err
+			}
+			s.info = info
+			continue
+		}
+		for len(v) >= headerSize+pageDataSize {
+			err := s.AddPage(v[:headerSize+pageDataSize])
+			if err != nil {
+				return err
+			}
+			v = v[headerSize+pageDataSize:]
+		}
+	}
+	return nil
+}
+
+// AddPage adds a raw database page to the store.
+// The page type and number are taken from the page header,
+// and a page with the same type and number is replaced.
+func (s *PageStore) AddPage(v []byte) error {
+	page, err := UnmarshalPageHeader(v)
+	if err != nil {
+		return err
+	}
+	m := s.pages[page.Type]
+	if m == nil {
+		m = make(map[int][]byte)
+		s.pages[page.Type] = m
+	}
+	m[page.Number] = append([]byte(nil), v...)
+	return nil
+}
+
+// Error returns the error state of the PageStore.
+func (s *PageStore) Error() error {
+	return s.err
+}
+
+// SetError sets the error state of the PageStore.
+func (s *PageStore) SetError(err error) {
+	s.err = err
+}
+
+func (s *PageStore) readPageRange(_ context.Context, pageType PageType) (int, int, error) {
+	first, last := -1, -1
+	for n := range s.pages[pageType] {
+		if first == -1 || n < first {
+			first = n
+		}
+		if last == -1 || n > last {
+			last = n
+		}
+	}
+	return first, last, nil
+}
+
+func (s *PageStore) readPage(_ context.Context, pageType PageType, pageNumber int) ([]byte, error) {
+	v, found := s.pages[pageType][pageNumber]
+	if !found {
+		return nil, fmt.Errorf("%v page %d is not in page store", pageType, pageNumber)
+	}
+	return v, nil
+}
+
+func (s *PageStore) readPages(ctx context.Context, pageType PageType, firstPage int, count int) ([][]byte, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("%v: invalid page count %d", pageType, count)
+	}
+	pages := make([][]byte, 0, count)
+	for n := firstPage; n < firstPage+count; n++ {
+		v, err := s.readPage(ctx, pageType, n)
+		if err != nil {
+			return pages, err
+		}
+		pages = append(pages, v)
+	}
+	return pages, nil
+}
+
+func (s *PageStore) partitionInfo(_ context.Context) *PartitionInfo {
+	return s.info
+}
+
+// SetLenient sets whether records with invalid CRCs are skipped
+// when reading records, rather than causing an error.
+// If skipped is non-nil, it is called for each skipped record;
+// if it is nil, lenient mode is disabled.
+func (s *PageStore) SetLenient(skipped CRCErrorFunc) {
+	s.skipped = skipped
+}
+
+func (s *PageStore) lenient() CRCErrorFunc {
+	return s.skipped
+}
+
+// ReadPageRange returns the first and last stored page of the given type,
+// or -1 and -1 if there are none.
+func (s *PageStore) ReadPageRange(pageType PageType) (int, int) {
+	if s.err != nil {
+		return -1, -1
+	}
+	first, last, _ := s.readPageRange(context.Background(), pageType)
+	return first, last
+}
+
+// ReadPage returns the specified stored page.
+func (s *PageStore) ReadPage(pageType PageType, pageNumber int) []byte {
+	if s.err != nil {
+		return nil
+	}
+	v, err := s.readPage(context.Background(), pageType, pageNumber)
+	s.err = err
+	return v
+}
+
+// ReadRecords returns the records in the specified stored page.
+func (s *PageStore) ReadRecords(pageType PageType, pageNumber int) Records {
+	if s.err != nil {
+		return nil
+	}
+	records, err := readRecords(context.Background(), s, pageType, pageNumber)
+	s.err = err
+	return records
+}
+
+// IterRecords applies recordFn to each record in the specified range
+// of stored pages, in the same order as CGM.IterRecords.
+func (s *PageStore) IterRecords(pageType PageType, firstPage, lastPage int, recordFn RecordFunc) {
+	if s.err != nil {
+		return
+	}
+	s.err = iterRecords(context.Background(), s, pageType, firstPage, lastPage, recordFn)
+}
+
+// ReadHistory returns stored records since the specified time.
+func (s *PageStore) ReadHistory(pageType PageType, since time.Time) Records {
+	if s.err != nil {
+		return nil
+	}
+	results, err := readHistory(context.Background(), s, pageType, since)
+	s.err = err
+	return results
+}
+
+// ReadRange returns stored records with times from "from" to "to", inclusive,
+// as described for CGM.ReadRange.
+func (s *PageStore) ReadRange(pageType PageType, from, to time.Time) Records {
+	if s.err != nil {
+		return nil
+	}
+	results, err := readRange(context.Background(), s, pageType, from, to)
+	s.err = err
+	return results
+}
+
+// ReadCount returns a specified number of most recent stored records.
+func (s *PageStore) ReadCount(pageType PageType, count int) Records {
+	if s.err != nil {
+		return nil
+	}
+	results, err := readCount(context.Background(), s, pageType, count)
+	s.err = err
+	return results
+}
+
+// ReadXMLRecord returns the given XML record type from the stored pages.
+func (s *PageStore) ReadXMLRecord(pageType PageType) Record {
+	if s.err != nil {
+		return Record{}
+	}
+	x, err := readXMLRecord(context.Background(), s, pageType)
+	s.err = err
+	return x
+}
+
+// ReadDisplayTime returns the receiver's display time when the pages were saved.
+// If that is not known, it returns the display time of the most recent stored EGV record.
+func (s *PageStore) ReadDisplayTime() time.Time {
+	if s.err != nil {
+		return time.Time{}
+	}
+	if !s.displayTime.IsZero() {
+		return s.displayTime
+	}
+	records := s.ReadCount(EGVData, 1)
+	if len(records) == 0 {
+		s.SetError(fmt.Errorf("display time is unknown"))
+		return time.Time{}
+	}
+	return records[0].Time()
+}
@@ -0,0 +1,134 @@
+package dexcom
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// checkSource compares the records read from src with the test data.
+func checkSource(t *testing.T, src Source) {
+	for _, c := range emulatorPages {
+		t.Run(c.pageType.String(), func(t *testing.T) {
+			first, last := src.ReadPageRange(c.pageType)
+			if first != c.pageNumber || last != c.pageNumber {
+				t.Errorf("ReadPageRange(%v) == (%d, %d), want (%d, %d)", c.pageType, first, last, c.pageNumber, c.pageNumber)
+			}
+			records := src.ReadHistory(c.pageType, time.Time{})
+			if src.Error() != nil {
+				t.Fatal(src.Error())
+			}
+			checkRecords(t, records, testFileName(c)+".json")
+		})
+	}
+	if x := src.ReadXMLRecord(ManufacturingData); x.XML()["SerialNumber"] == "" {
+		t.Errorf("ReadXMLRecord(%v) == %v", ManufacturingData, x)
+	}
+	if latest := src.ReadCount(EGVData, 2); len(latest) != 2 {
+		t.Errorf("ReadCount(%v, 2) returned %d records", EGVData, len(latest))
+	}
+	if src.Error() != nil {
+		t.Error(src.Error())
+	}
+}
+
+func TestPageFiles(t *testing.T) {
+	var files []string
+	for _, c := range emulatorPages {
+		files = append(files, testFileName(c)+".data")
+	}
+	src := OpenSource(files...)
+	if src.Error() != nil {
+		t.Fatal(src.Error())
+	}
+	checkSource(t, src)
+	want := src.ReadCount(EGVData, 1)[0].Time()
+	if got := src.ReadDisplayTime(); !got.Equal(want) {
+		t.Errorf("ReadDisplayTime() == %v, want %v", got, want)
+	}
+}
+
+func TestArchiveSource(t *testing.T) {
+	cgm := &CGM{Connection: newTestEmulator(t)}
+	a := cgm.Backup()
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	dir, err := ioutil.TempDir("", "dexcom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "backup.json")
+	err = a.Save(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := OpenSource(file)
+	if src.Error() != nil {
+		t.Fatal(src.Error())
+	}
+	checkSource(t, src)
+	if got := src.ReadDisplayTime(); !got.Equal(a.DisplayTime) {
+		t.Errorf("ReadDisplayTime() == %v, want %v", got, a.DisplayTime)
+	}
+}
+
+func TestSessionSource(t *testing.T) {
+	var session bytes.Buffer
+	cgm := &CGM{Connection: NewRecorder(newTestEmulator(t), &session)}
+	for _, c := range emulatorPages {
+		cgm.ReadPage(c.pageType, c.pageNumber)
+	}
+	// Failed commands must not add pages.
+	cgm.ReadPageE(EGVData, 999)
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	s := NewPageStore()
+	err := s.load(session.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSource(t, s)
+}
+
+func TestSessionSourceInvalidPacket(t *testing.T) {
+	cmd := marshalPacket(ReadDatabasePages, []byte{byte(EGVData), 1, 0, 0, 0, 1})
+	resp := marshalPacket(Ack, make([]byte, headerSize+pageDataSize))
+	badStart := append([]byte{0}, resp[1:]...)
+	shortLength := append([]byte(nil), resp...)
+	copy(shortLength[1:3], marshalUint16(3))
+	badCRC := append([]byte(nil), resp...)
+	badCRC[len(badCRC)-1] ^= 0xFF
+	for _, r := range [][]byte{badStart, shortLength, badCRC} {
+		session := fmt.Sprintf("%c % X\n%c % X\n", sendPrefix, cmd, receivePrefix, r)
+		err := NewPageStore().load([]byte(session))
+		if err == nil {
+			t.Errorf("loading session with response % X succeeded, want error", r[:4])
+		}
+	}
+}
+
+func TestPageStoreMissingPage(t *testing.T) {
+	s := NewPageStore()
+	s.ReadRecords(EGVData, 1)
+	if s.Error() == nil {
+		t.Errorf("ReadRecords succeeded on empty page store, want error")
+	}
+	s.SetError(nil)
+	if records := s.ReadHistory(EGVData, time.Time{}); len(records) != 0 || s.Error() != nil {
+		t.Errorf("ReadHistory == %v, %v; want no records", records, s.Error())
+	}
+}
+
+func TestLoadPagesError(t *testing.T) {
+	src := OpenSource(testDataDir + "/nonexistent.data")
+	if src.Error() == nil {
+		t.Errorf("OpenSource succeeded with nonexistent file, want error")
+	}
+}
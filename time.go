@@ -36,6 +36,10 @@ func unmarshalTime(v []byte) time.Time {
 	return toTime(int64(unmarshalUint32(v)))
 }
 
+func marshalTime(t time.Time) []byte {
+	return marshalUint32(uint32(fromTime(t)))
+}
+
 // A Timestamp contains system and display time values.
 type Timestamp struct {
 	SystemTime  time.Time
@@ -47,34 +51,80 @@ func (r *Timestamp) unmarshal(v []byte) {
 	r.DisplayTime = unmarshalTime(v[4:8])
 }
 
+func (r Timestamp) marshal() []byte {
+	return append(marshalTime(r.SystemTime), marshalTime(r.DisplayTime)...)
+}
+
 func displayTime(sys uint32, offset int32) time.Time {
 	return toTime(int64(sys) + int64(offset))
 }
 
+// ReadSystemTime returns the Dexcom receiver's system time.
+func (cgm *CGM) ReadSystemTime() time.Time {
+	if cgm.Error() != nil {
+		return time.Time{}
+	}
+	t, err := cgm.ReadSystemTimeE()
+	cgm.SetError(err)
+	return t
+}
+
+// ReadSystemTimeE is like ReadSystemTime, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadSystemTimeE() (time.Time, error) {
+	v, err := cgm.CmdE(ReadSystemTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return unmarshalTime(v), nil
+}
+
 // ReadDisplayTime returns the Dexcom receiver's display time.
+//
 //	SystemTime = RTC + SystemTimeOffset
 //	DisplayTime = SystemTime + DisplayTimeOffset
 func (cgm *CGM) ReadDisplayTime() time.Time {
-	v := cgm.Cmd(ReadDisplayTimeOffset)
 	if cgm.Error() != nil {
 		return time.Time{}
 	}
+	t, err := cgm.ReadDisplayTimeE()
+	cgm.SetError(err)
+	return t
+}
+
+// ReadDisplayTimeE is like ReadDisplayTime, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadDisplayTimeE() (time.Time, error) {
+	v, err := cgm.CmdE(ReadDisplayTimeOffset)
+	if err != nil {
+		return time.Time{}, err
+	}
 	displayOffset := unmarshalInt32(v)
-	v = cgm.Cmd(ReadSystemTime)
-	if cgm.Error() != nil {
-		return time.Time{}
+	v, err = cgm.CmdE(ReadSystemTime)
+	if err != nil {
+		return time.Time{}, err
 	}
 	sysTime := unmarshalUint32(v)
-	return displayTime(sysTime, displayOffset)
+	return displayTime(sysTime, displayOffset), nil
 }
 
 // SetDisplayTime sets the Dexcom receiver's display time.
 func (cgm *CGM) SetDisplayTime(t time.Time) {
-	v := cgm.Cmd(ReadSystemTime)
 	if cgm.Error() != nil {
 		return
 	}
+	cgm.SetError(cgm.SetDisplayTimeE(t))
+}
+
+// SetDisplayTimeE is like SetDisplayTime, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) SetDisplayTimeE(t time.Time) error {
+	v, err := cgm.CmdE(ReadSystemTime)
+	if err != nil {
+		return err
+	}
 	sysTime := unmarshalUint32(v)
 	offset := int32(fromTime(t) - int64(sysTime))
-	cgm.Cmd(WriteDisplayTimeOffset, marshalInt32(offset)...)
+	_, err = cgm.CmdE(WriteDisplayTimeOffset, marshalInt32(offset)...)
+	return err
 }
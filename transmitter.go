@@ -0,0 +1,38 @@
+package dexcom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Characters used in transmitter IDs.
+// The letters I, O, V, and Z are not used,
+// so each character can be encoded in 5 bits.
+const transmitterIDChars = "0123456789ABCDEFGHJKLMNPQRSTUWXY"
+
+// encodeTransmitterID returns the encoding of a transmitter ID
+// as 5 bits per character, with the first character in the high-order bits.
+func encodeTransmitterID(id string) (uint32, error) {
+	if len(id) != transmitterIDLength {
+		return 0, fmt.Errorf("invalid transmitter ID %q", id)
+	}
+	n := uint32(0)
+	for i := 0; i < len(id); i++ {
+		c := strings.IndexByte(transmitterIDChars, id[i])
+		if c < 0 {
+			return 0, fmt.Errorf("invalid transmitter ID %q", id)
+		}
+		n = n<<5 | uint32(c)
+	}
+	return n, nil
+}
+
+// decodeTransmitterID returns the transmitter ID encoded by encodeTransmitterID.
+func decodeTransmitterID(n uint32) string {
+	id := make([]byte, transmitterIDLength)
+	for i := transmitterIDLength - 1; i >= 0; i-- {
+		id[i] = transmitterIDChars[n&0x1F]
+		n >>= 5
+	}
+	return string(id)
+}
@@ -0,0 +1,43 @@
+package dexcom
+
+import (
+	"testing"
+)
+
+func TestTransmitterID(t *testing.T) {
+	cases := []struct {
+		id string
+		n  uint32
+	}{
+		{"00000", 0},
+		{"00001", 1},
+		{"0000Y", 31},
+		{"6ABCD", 6<<20 | 10<<15 | 11<<10 | 12<<5 | 13},
+		{"6WXY0", 6<<20 | 29<<15 | 30<<10 | 31<<5},
+		{"YYYYY", 1<<25 - 1},
+		{"HJUWX", 17<<20 | 18<<15 | 28<<10 | 29<<5 | 30},
+	}
+	for _, c := range cases {
+		n, err := encodeTransmitterID(c.id)
+		if err != nil {
+			t.Errorf("encodeTransmitterID(%q) returned %v", c.id, err)
+			continue
+		}
+		if n != c.n {
+			t.Errorf("encodeTransmitterID(%q) == %d, want %d", c.id, n, c.n)
+		}
+		id := decodeTransmitterID(n)
+		if id != c.id {
+			t.Errorf("decodeTransmitterID(%d) == %q, want %q", n, id, c.id)
+		}
+	}
+}
+
+func TestInvalidTransmitterID(t *testing.T) {
+	for _, id := range []string{"", "6ABC", "6ABCDE", "6abcd", "6ABCI", "6ABCO", "6ABCV", "6ABCZ", "6ABC-"} {
+		_, err := encodeTransmitterID(id)
+		if err == nil {
+			t.Errorf("encodeTransmitterID(%q) succeeded, want error", id)
+		}
+	}
+}
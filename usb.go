@@ -2,6 +2,7 @@ package dexcom
 
 import (
 	"log"
+	"time"
 
 	"github.com/ecc1/serial"
 )
@@ -12,7 +13,17 @@ const (
 	dexcomProduct = 0x0047
 )
 
-type usbConn serial.Port
+// usbPort is the subset of serial.Port used by a USB connection.
+type usbPort interface {
+	Read([]byte) error
+	Write([]byte) error
+	Close() error
+}
+
+type usbConn struct {
+	port usbPort
+	rx   *byteQueue
+}
 
 // OpenUSB opens the USB serial device for a Dexcom G4 receiver.
 func OpenUSB() (Connection, error) {
@@ -25,20 +36,57 @@ func OpenUSB() (Connection, error) {
 		return nil, err
 	}
 	port, err := serial.Open(device, 115200)
-	return (*usbConn)(port), err
+	if err != nil {
+		return nil, err
+	}
+	return newUSBConn(port, receiveTimeout), nil
+}
+
+// newUSBConn returns a connection that reads from the port
+// in the background, so that Receive can time out and Drain
+// can discard data without blocking.
+func newUSBConn(port usbPort, timeout time.Duration) *usbConn {
+	conn := &usbConn{port: port, rx: newByteQueue(timeout)}
+	go conn.read()
+	return conn
+}
+
+func (conn *usbConn) read() {
+	b := make([]byte, 1)
+	for {
+		err := conn.port.Read(b)
+		if err != nil {
+			conn.rx.close(err)
+			return
+		}
+		conn.rx.c <- b[0]
+	}
 }
 
 // Send writes data over the USB connection.
 func (conn *usbConn) Send(data []byte) error {
-	return (*serial.Port)(conn).Write(data)
+	return conn.port.Write(data)
 }
 
 // Receive reads data from the USB connection.
 func (conn *usbConn) Receive(data []byte) error {
-	return (*serial.Port)(conn).Read(data)
+	return conn.rx.receive(data)
+}
+
+// Drain discards any data waiting to be received over the USB connection,
+// including the rest of a response that is still arriving.
+func (conn *usbConn) Drain() {
+	conn.rx.drain(drainTimeout)
+}
+
+// SetDeadline sets the time after which Receive fails.
+// A zero time means no deadline.
+// Sending is not affected.
+func (conn *usbConn) SetDeadline(t time.Time) error {
+	return conn.rx.SetDeadline(t)
 }
 
 // Close closes the USB connection.
 func (conn *usbConn) Close() {
-	_ = (*serial.Port)(conn).Close()
+	_ = conn.port.Close()
 }
@@ -0,0 +1,114 @@
+package dexcom
+
+import (
+	"time"
+)
+
+// UserEventInfo represents an event entered on the receiver.
+type UserEventInfo struct {
+	Type      UserEventType
+	SubType   byte
+	EventTime time.Time
+	Value     uint32
+}
+
+// UserEventType represents the kind of a user event.
+type UserEventType byte
+
+//go:generate stringer -type UserEventType
+
+// User event types.
+const (
+	CarbsEvent    UserEventType = 1
+	InsulinEvent  UserEventType = 2
+	HealthEvent   UserEventType = 3
+	ExerciseEvent UserEventType = 4
+)
+
+// HealthSubtype represents the kind of a health event.
+type HealthSubtype byte
+
+//go:generate stringer -type HealthSubtype
+
+// Health event subtypes.
+const (
+	Illness      HealthSubtype = 1
+	Stress       HealthSubtype = 2
+	HighSymptoms HealthSubtype = 3
+	LowSymptoms  HealthSubtype = 4
+	Cycle        HealthSubtype = 5
+	Alcohol      HealthSubtype = 6
+)
+
+// ExerciseSubtype represents the intensity of an exercise event.
+type ExerciseSubtype byte
+
+//go:generate stringer -type ExerciseSubtype
+
+// Exercise event subtypes.
+const (
+	LightExercise  ExerciseSubtype = 1
+	MediumExercise ExerciseSubtype = 2
+	HeavyExercise  ExerciseSubtype = 3
+)
+
+func unmarshalUserEventInfo(r *Record, v []byte) {
+	r.Info = &UserEventInfo{
+		Type:      UserEventType(v[8]),
+		SubType:   v[9],
+		EventTime: unmarshalTime(v[10:14]),
+		Value:     unmarshalUint32(v[14:18]),
+	}
+}
+
+func marshalUserEventInfo(r *Record) []byte {
+	e := r.Info.(*UserEventInfo)
+	if e == nil {
+		return nil
+	}
+	v := append(r.Timestamp.marshal(), byte(e.Type), e.SubType)
+	v = append(v, marshalTime(e.EventTime)...)
+	return append(v, marshalUint32(e.Value)...)
+}
+
+// Carbs returns the grams of carbohydrate in a carbs event.
+func (e UserEventInfo) Carbs() int {
+	if e.Type != CarbsEvent {
+		return 0
+	}
+	return int(e.Value)
+}
+
+// Insulin returns the units of insulin in an insulin event.
+// The receiver stores the value in hundredths of a unit.
+func (e UserEventInfo) Insulin() float64 {
+	if e.Type != InsulinEvent {
+		return 0
+	}
+	return float64(e.Value) / 100
+}
+
+// Health returns the subtype of a health event.
+func (e UserEventInfo) Health() HealthSubtype {
+	if e.Type != HealthEvent {
+		return 0
+	}
+	return HealthSubtype(e.SubType)
+}
+
+// Exercise returns the intensity of an exercise event.
+func (e UserEventInfo) Exercise() ExerciseSubtype {
+	if e.Type != ExerciseEvent {
+		return 0
+	}
+	return ExerciseSubtype(e.SubType)
+}
+
+// Duration returns the duration of an exercise event.
+// The receiver stores the value in minutes.
+func (e UserEventInfo) Duration() time.Duration {
+	if e.Type != ExerciseEvent {
+		return 0
+	}
+	return time.Duration(e.Value) * time.Minute
+}
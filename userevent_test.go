@@ -0,0 +1,73 @@
+package dexcom
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserEvents(t *testing.T) {
+	t0 := parseTime("2017-09-17 11:00:00")
+	at := func(min int) time.Time { return t0.Add(time.Duration(min) * time.Minute) }
+	page := makePage(UserEventData, 3,
+		testRecord(at(8), &UserEventInfo{Type: ExerciseEvent, SubType: byte(HeavyExercise), EventTime: at(3), Value: 40}),
+		testRecord(at(7), &UserEventInfo{Type: HealthEvent, SubType: byte(Stress), EventTime: at(2)}),
+		testRecord(at(6), &UserEventInfo{Type: InsulinEvent, EventTime: at(1), Value: 325}),
+		testRecord(at(5), &UserEventInfo{Type: CarbsEvent, EventTime: at(0), Value: 45}),
+	)
+	e := NewEmulator()
+	err := e.AddPage(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cgm := &CGM{Connection: e}
+	records := cgm.ReadHistory(UserEventData, time.Time{})
+	if cgm.Error() != nil {
+		t.Fatal(cgm.Error())
+	}
+	if len(records) != 4 {
+		t.Fatalf("ReadHistory returned %d records, want 4", len(records))
+	}
+	ex, health, insulin, carbs := records[0].Info.(*UserEventInfo), records[1].Info.(*UserEventInfo), records[2].Info.(*UserEventInfo), records[3].Info.(*UserEventInfo)
+	if carbs.Type != CarbsEvent || carbs.Carbs() != 45 || !carbs.EventTime.Equal(at(0)) {
+		t.Errorf("carbs event = %+v", carbs)
+	}
+	if insulin.Type != InsulinEvent || insulin.Insulin() != 3.25 || insulin.Carbs() != 0 {
+		t.Errorf("insulin event = %+v", insulin)
+	}
+	if health.Type != HealthEvent || health.Health() != Stress {
+		t.Errorf("health event = %+v", health)
+	}
+	if ex.Type != ExerciseEvent || ex.Exercise() != HeavyExercise || ex.Duration() != 40*time.Minute {
+		t.Errorf("exercise event = %+v", ex)
+	}
+	treatments, skipped := NightscoutTreatments(records)
+	if len(treatments) != 4 || len(skipped) != 0 {
+		t.Fatalf("NightscoutTreatments returned %d treatments and %d skipped events, want 4 and 0", len(treatments), len(skipped))
+	}
+	cases := []struct {
+		eventType string
+		notes     string
+	}{
+		{"Exercise", "HeavyExercise"},
+		{"Note", "Stress"},
+		{"Correction Bolus", ""},
+		{"Carb Correction", ""},
+	}
+	for i, c := range cases {
+		tr := treatments[i]
+		if tr.EventType != c.eventType || tr.Notes != c.notes || !tr.CreatedAt.Equal(records[i].Info.(*UserEventInfo).EventTime) {
+			t.Errorf("treatment %d = %+v, want %s %q", i, tr, c.eventType, c.notes)
+		}
+	}
+	if *treatments[0].Duration != 40 || *treatments[2].Insulin != 3.25 || *treatments[3].Carbs != 45 {
+		t.Errorf("treatments = %+v", treatments)
+	}
+	unknown := Record{Info: &UserEventInfo{Type: UserEventType(9), EventTime: at(0)}}
+	treatments, skipped = NightscoutTreatments(append(records, unknown))
+	if len(treatments) != 4 || len(skipped) != 1 || skipped[0].Info != unknown.Info {
+		t.Errorf("NightscoutTreatments returned %d treatments and skipped %+v, want 4 and [%+v]", len(treatments), skipped, unknown)
+	}
+	if entries := NightscoutEntries(records); len(entries) != 0 {
+		t.Errorf("NightscoutEntries returned %d entries for user events, want 0", len(entries))
+	}
+}
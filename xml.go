@@ -2,7 +2,9 @@ package dexcom
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
+	"sort"
 )
 
 // XMLInfo maps attribute names to values.
@@ -10,13 +12,50 @@ import (
 // with multiple attributes, so a tree structure is not required.
 type XMLInfo map[string]string
 
-func umarshalXMLInfo(r *Record, v []byte) {
+type (
+	// ManufacturingInfo represents a ManufacturingData record.
+	ManufacturingInfo XMLInfo
+
+	// FirmwareInfo represents a FirmwareData record.
+	FirmwareInfo XMLInfo
+
+	// SoftwareInfo represents a SoftwareData record.
+	SoftwareInfo XMLInfo
+)
+
+// XML returns the attributes of a ManufacturingData, FirmwareData,
+// or SoftwareData record, or nil for other records.
+func (r Record) XML() XMLInfo {
+	switch info := r.Info.(type) {
+	case ManufacturingInfo:
+		return XMLInfo(info)
+	case FirmwareInfo:
+		return XMLInfo(info)
+	case SoftwareInfo:
+		return XMLInfo(info)
+	}
+	return nil
+}
+
+func unmarshalManufacturingInfo(r *Record, v []byte) {
+	r.Info = ManufacturingInfo(unmarshalXMLRecord(v))
+}
+
+func unmarshalFirmwareInfo(r *Record, v []byte) {
+	r.Info = FirmwareInfo(unmarshalXMLRecord(v))
+}
+
+func unmarshalSoftwareInfo(r *Record, v []byte) {
+	r.Info = SoftwareInfo(unmarshalXMLRecord(v))
+}
+
+func unmarshalXMLRecord(v []byte) XMLInfo {
 	v = v[8:]
 	i := bytes.IndexByte(v, 0x00)
 	if i != -1 {
 		v = v[:i]
 	}
-	r.XML = umarshalXMLBytes(v)
+	return umarshalXMLBytes(v)
 }
 
 func umarshalXMLBytes(v []byte) XMLInfo {
@@ -28,6 +67,51 @@ func umarshalXMLBytes(v []byte) XMLInfo {
 	return m
 }
 
+func marshalManufacturingInfo(r *Record) []byte {
+	return marshalXMLInfo(r, "ManufacturingParameters")
+}
+
+func marshalFirmwareInfo(r *Record) []byte {
+	return marshalXMLInfo(r, "FirmwareParameters")
+}
+
+func marshalSoftwareInfo(r *Record) []byte {
+	return marshalXMLInfo(r, "SoftwareParameters")
+}
+
+// marshalXMLInfo marshals an XML record as an element with the given name.
+// Element names are not preserved by unmarshaling.
+func marshalXMLInfo(r *Record, name string) []byte {
+	m := r.XML()
+	if m == nil {
+		return nil
+	}
+	v := r.Timestamp.marshal()
+	if s, invalid := m["InvalidXML"]; invalid {
+		return append(v, s...)
+	}
+	return append(v, m.marshal(name)...)
+}
+
+// marshal returns a single XML element with the given name,
+// with attributes in sorted order.
+func (m XMLInfo) marshal(name string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	buf.WriteString("<" + name)
+	for _, k := range keys {
+		buf.WriteString(" " + k + `="`)
+		_ = xml.EscapeText(&buf, []byte(m[k]))
+		buf.WriteString(`"`)
+	}
+	buf.WriteString(" />")
+	return buf.Bytes()
+}
+
 // UnmarshalXML is called by xml.Unmarshal.
 func (ptr *XMLInfo) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	m := *ptr
@@ -40,20 +124,46 @@ func (ptr *XMLInfo) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 // ReadFirmwareHeader gets the firmware header from the Dexcom CGM receiver
 // and returns it as XMLInfo.
 func (cgm *CGM) ReadFirmwareHeader() XMLInfo {
-	v := cgm.Cmd(ReadFirmwareHeader)
 	if cgm.Error() != nil {
 		return nil
 	}
-	return umarshalXMLBytes(v)
+	x, err := cgm.ReadFirmwareHeaderE()
+	cgm.SetError(err)
+	return x
+}
+
+// ReadFirmwareHeaderE is like ReadFirmwareHeader, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadFirmwareHeaderE() (XMLInfo, error) {
+	v, err := cgm.CmdE(ReadFirmwareHeader)
+	if err != nil {
+		return nil, err
+	}
+	return umarshalXMLBytes(v), nil
 }
 
 // ReadXMLRecord gets the given XML record type from the Dexcom CGM receiver.
 func (cgm *CGM) ReadXMLRecord(pageType PageType) Record {
+	if cgm.Error() != nil {
+		return Record{}
+	}
+	x, err := cgm.ReadXMLRecordE(pageType)
+	cgm.SetError(err)
+	return x
+}
+
+// ReadXMLRecordE is like ReadXMLRecord, but it returns any error
+// instead of using the CGM's error state.
+func (cgm *CGM) ReadXMLRecordE(pageType PageType) (Record, error) {
+	return readXMLRecord(context.Background(), cgm, pageType)
+}
+
+func readXMLRecord(ctx context.Context, src pageReader, pageType PageType) (Record, error) {
 	x := Record{}
 	proc := func(r Record) error {
 		x = r
 		return IterationDone
 	}
-	cgm.IterRecords(pageType, 0, 0, proc)
-	return x
+	err := iterRecords(ctx, src, pageType, 0, 0, proc)
+	return x, err
 }